@@ -0,0 +1,335 @@
+package gee
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind picks a binding from the request method and Content-Type (see
+// ShouldBind). On error it aborts with 400 Bad Request and records a bind
+// error, which ErrorHandler renders
+func (c *Context) Bind(obj interface{}) error {
+	if err := c.ShouldBind(obj); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+// ShouldBind binds the request into obj without writing a response:
+// GET, HEAD and DELETE requests bind the query string, other requests bind
+// the body according to Content-Type (JSON, XML, or form by default)
+func (c *Context) ShouldBind(obj interface{}) error {
+	switch c.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return c.ShouldBindQuery(obj)
+	}
+
+	contentType := c.Req.Header.Get("Content-Type")
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	switch strings.TrimSpace(contentType) {
+	case MIMEJSON:
+		return c.ShouldBindJSON(obj)
+	case MIMEXML, MIMEXML2:
+		return c.ShouldBindXML(obj)
+	default:
+		return c.ShouldBindForm(obj)
+	}
+}
+
+// ShouldBindJSON decodes the request body as JSON into obj, then validates it
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if c.Req.Body == nil {
+		return errors.New("gee: missing request body")
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ShouldBindXML decodes the request body as XML into obj, then validates it
+func (c *Context) ShouldBindXML(obj interface{}) error {
+	if c.Req.Body == nil {
+		return errors.New("gee: missing request body")
+	}
+	if err := xml.NewDecoder(c.Req.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ShouldBindQuery maps the query string into the `form` tags of obj
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	if err := mapForm(obj, c.Req.URL.Query(), "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ShouldBindForm maps the query string and the url-encoded or multipart
+// body into the `form` tags of obj
+func (c *Context) ShouldBindForm(obj interface{}) error {
+	if _, err := c.MultipartForm(); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	if err := mapForm(obj, c.Req.Form, "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ShouldBindUri maps the route params into the `uri` tags of obj
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for key, value := range c.Params {
+		values[key] = []string{value}
+	}
+	if err := mapForm(obj, values, "uri"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// mapForm fills the struct pointed to by obj from values, looking fields up
+// by tag. `form:"name,default=10"` supplies a value when the key is absent,
+// `form:"-"` skips the field and untagged fields use the field name
+func mapForm(obj interface{}, values map[string][]string, tag string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("gee: binding requires a non-nil struct pointer, got %T", obj)
+	}
+	return mapStruct(v.Elem(), values, tag)
+}
+
+func mapStruct(v reflect.Value, values map[string][]string, tag string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && (!field.Anonymous || field.Type.Kind() != reflect.Struct) {
+			continue // unexported, except embedded structs with exported fields
+		}
+
+		name, opts := field.Name, ""
+		if tagValue, ok := field.Tag.Lookup(tag); ok {
+			if tagValue == "-" {
+				continue
+			}
+			name, opts = tagValue, ""
+			if i := strings.IndexByte(tagValue, ','); i >= 0 {
+				name, opts = tagValue[:i], tagValue[i+1:]
+			}
+			if name == "" {
+				name = field.Name
+			}
+		}
+
+		fv := v.Field(i)
+		vals, ok := values[name]
+		if !ok && strings.HasPrefix(opts, "default=") {
+			vals, ok = []string{strings.TrimPrefix(opts, "default=")}, true
+		}
+		if !ok {
+			// embedded and nested structs look their fields up in the same values
+			if ft := indirectType(field.Type); ft.Kind() == reflect.Struct && ft != timeType {
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						fv.Set(reflect.New(ft))
+					}
+					fv = fv.Elem()
+				}
+				if err := mapStruct(fv, values, tag); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := setField(fv, field, vals); err != nil {
+			return fmt.Errorf("gee: binding field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func setField(v reflect.Value, field reflect.StructField, vals []string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), field, vals); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break // []byte is a single value
+		}
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), field, val); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(vals) != v.Len() {
+			return fmt.Errorf("expected %d values, got %d", v.Len(), len(vals))
+		}
+		for i, val := range vals {
+			if err := setValue(v.Index(i), field, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(vals) == 0 {
+		return nil
+	}
+	return setValue(v, field, vals[0])
+}
+
+func setValue(v reflect.Value, field reflect.StructField, val string) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), field, val); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		return setTime(v, field, val)
+	case durationType:
+		if val == "" {
+			val = "0"
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(val))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Slice: // []byte
+		v.SetBytes([]byte(val))
+	case reflect.Bool:
+		if val == "" {
+			val = "false"
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val == "" {
+			val = "0"
+		}
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val == "" {
+			val = "0"
+		}
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if val == "" {
+			val = "0"
+		}
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// setTime parses val with the `time_format` tag, a Go layout or "unix" /
+// "unixnano", RFC 3339 by default. `time_utc:"1"` and `time_location` pick
+// the location of layouts without a zone
+func setTime(v reflect.Value, field reflect.StructField, val string) error {
+	if val == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+
+	layout := field.Tag.Get("time_format")
+	switch layout {
+	case "":
+		layout = time.RFC3339
+	case "unix", "unixnano":
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		t := time.Unix(n, 0)
+		if layout == "unixnano" {
+			t = time.Unix(0, n)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	loc := time.Local
+	if utc, _ := strconv.ParseBool(field.Tag.Get("time_utc")); utc {
+		loc = time.UTC
+	}
+	if name := field.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return err
+		}
+		loc = l
+	}
+
+	t, err := time.ParseInLocation(layout, val, loc)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
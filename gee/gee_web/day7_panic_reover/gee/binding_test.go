@@ -0,0 +1,110 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindAddress struct {
+	City string `form:"city" json:"city" binding:"required"`
+}
+
+type bindUser struct {
+	ID       int       `uri:"id" binding:"required,min=1"`
+	Name     string    `form:"name" json:"name" binding:"required,min=2,max=10"`
+	Email    string    `form:"email" json:"email" binding:"omitempty,email"`
+	Age      *uint8    `form:"age" json:"age"`
+	Admin    bool      `form:"admin" json:"admin"`
+	Tags     []string  `form:"tag" json:"tags" binding:"max=3"`
+	Page     int       `form:"page,default=1" json:"-"`
+	Birthday time.Time `form:"birthday" time_format:"2006-01-02" time_utc:"1" json:"-"`
+	Role     string    `form:"role" json:"role" binding:"omitempty,oneof=admin user"`
+	Address  bindAddress
+}
+
+func newBindContext(method, target, contentType, body string) *Context {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return New().acquireContext(httptest.NewRecorder(), req)
+}
+
+func TestShouldBindQuery(t *testing.T) {
+	c := newBindContext("GET", "/?name=geektutu&age=18&admin=true&tag=a&tag=b&birthday=2021-05-24&city=hz", "", "")
+	var u bindUser
+	if err := c.ShouldBindQuery(&u); err == nil {
+		t.Fatal("ID is required and only bound from the uri")
+	}
+
+	u.ID = 1
+	if err := c.ShouldBind(&u); err != nil {
+		t.Fatal(err)
+	}
+	birthday := time.Date(2021, 5, 24, 0, 0, 0, 0, time.UTC)
+	if u.Name != "geektutu" || u.Age == nil || *u.Age != 18 || !u.Admin || u.Page != 1 ||
+		!reflect.DeepEqual(u.Tags, []string{"a", "b"}) || !u.Birthday.Equal(birthday) || u.Address.City != "hz" {
+		t.Fatalf("unexpected binding result %+v", u)
+	}
+}
+
+func TestShouldBindFormAndUri(t *testing.T) {
+	c := newBindContext("POST", "/user/7?page=3", "application/x-www-form-urlencoded", "name=gee&city=hz&role=admin")
+	c.Params = map[string]string{"id": "7"}
+	var u bindUser
+	if err := c.ShouldBindUri(&u); err == nil {
+		t.Fatal("form fields are required but missing from the uri")
+	}
+	if err := c.ShouldBind(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 7 || u.Name != "gee" || u.Page != 3 || u.Role != "admin" {
+		t.Fatalf("unexpected binding result %+v", u)
+	}
+
+	c = newBindContext("GET", "/?age=abc", "", "")
+	if err := c.ShouldBindQuery(&u); err == nil || !strings.Contains(err.Error(), "Age") {
+		t.Fatalf("expected a conversion error for Age, got %v", err)
+	}
+}
+
+func TestShouldBindJSONValidation(t *testing.T) {
+	c := newBindContext("POST", "/", "application/json",
+		`{"name":"g","email":"not-an-email","tags":["a","b","c","d"],"role":"root"}`)
+	var u bindUser
+	err := c.ShouldBind(&u)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	var failed []string
+	for _, e := range errs {
+		failed = append(failed, e.Field+":"+e.Rule)
+	}
+	expected := []string{"ID:required", "Name:min", "Email:email", "Tags:max", "Role:oneof", "Address.City:required"}
+	if !reflect.DeepEqual(failed, expected) {
+		t.Fatalf("expected failures %v, got %v", expected, failed)
+	}
+}
+
+func TestBindAbortsWithBadRequest(t *testing.T) {
+	r := New()
+	r.POST("/user", func(ctx *Context) {
+		var u bindUser
+		if ctx.Bind(&u) != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/user", strings.NewReader("{")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
@@ -1,38 +1,128 @@
 package gee
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"encoding/xml"
 	"fmt"
+	"gee/render"
+	"io"
+	"math"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 )
 
 type H map[string]interface{}
 
+// MarshalXML encodes H as <map><key>value</key>...</map>, so gee.H works with XML
+func (h H) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "map"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		elem := xml.StartElement{Name: xml.Name{Local: key}}
+		if err := e.EncodeElement(h[key], elem); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
 type Context struct {
 	// origin objects
-	Writer http.ResponseWriter
-	Req    *http.Request
+	writermem responseWriter
+	Writer    ResponseWriter
+	Req       *http.Request
 	// request info
 	Path   string
 	Method string
 	Params map[string]string
-	// response info
-	StatusCode int
+	// pattern of the matched route, e.g. /p/:lang
+	fullPath string
+	// group of the matched route, nil when no route matched
+	group *RouterGroup
 	// middleware
 	handlers []HandlerFunc
 	index    int
 	// engine pointer
 	engine *Engine
+	// per-request key/value store, see Set and Get
+	mu   sync.RWMutex
+	Keys map[string]interface{}
+	// errors collected by handlers and middlewares, see Error
+	Errors errorMsgs
+	// SameSite attribute of the cookies set with SetCookie
+	sameSite http.SameSite
+}
+
+// abortIndex is beyond any handler chain, Next stops once index reaches it
+const abortIndex int = math.MaxInt32 / 2
+
+var _ context.Context = &Context{}
+
+// reset prepares a pooled Context for the request in c.Req
+func (c *Context) reset() {
+	c.Writer = &c.writermem
+	c.Path = c.Req.URL.Path
+	c.Method = c.Req.Method
+	c.fullPath = ""
+	c.group = nil
+	for key := range c.Params {
+		delete(c.Params, key)
+	}
+	c.handlers = nil
+	c.index = -1
+	c.Keys = nil
+	c.Errors = c.Errors[:0]
+	c.sameSite = http.SameSiteDefaultMode
 }
 
-func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Writer: w,
-		Req:    req,
-		Path:   req.URL.Path,
-		Method: req.Method,
-		index:  -1,
+// Copy returns a copy of c that can be used outside the request, e.g. in a
+// goroutine, because c itself is recycled once the handlers return
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Req:      c.Req,
+		Path:     c.Path,
+		Method:   c.Method,
+		Params:   make(map[string]string, len(c.Params)),
+		fullPath: c.fullPath,
+		group:    c.group,
+		index:    abortIndex,
+		engine:   c.engine,
+	}
+	cp.writermem = c.writermem
+	cp.writermem.ResponseWriter = nil
+	cp.Writer = &cp.writermem
+	for key, value := range c.Params {
+		cp.Params[key] = value
+	}
+
+	c.mu.RLock()
+	if c.Keys != nil {
+		cp.Keys = make(map[string]interface{}, len(c.Keys))
+		for key, value := range c.Keys {
+			cp.Keys[key] = value
+		}
 	}
+	c.mu.RUnlock()
+	return cp
 }
 
 func (c *Context) Next() {
@@ -43,21 +133,180 @@ func (c *Context) Next() {
 	}
 }
 
+// Abort prevents the pending handlers from running, the current one
+// still finishes. It writes nothing, so the response is left to the caller
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether the chain has been aborted
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus aborts the chain and responds code without a body
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+// AbortWithStatusJSON aborts the chain and responds code with obj as JSON
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
+// AbortWithError aborts the chain with code and records err, the body is
+// left to an error handling middleware such as ErrorHandler
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.AbortWithStatus(code)
+	return c.Error(err)
+}
+
+// FullPath returns the pattern of the matched route, e.g. /p/:lang,
+// or "" when no route matched
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
 func (c *Context) Param(key string) string {
 	value, _ := c.Params[key]
 	return value
 }
 
+// ParamInt returns the route parameter key parsed as an int
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+// ParamUUID returns the route parameter key as a lowercase UUID,
+// an error if it isn't in the 8-4-4-4-12 hex form
+func (c *Context) ParamUUID(key string) (string, error) {
+	value := c.Param(key)
+	if !uuidPattern.MatchString(value) {
+		return "", fmt.Errorf("gee: parameter '%s' is not a UUID: %q", key, value)
+	}
+	return strings.ToLower(value), nil
+}
+
+var uuidPattern = regexp.MustCompile("^" + paramConstraints["uuid"] + "$")
+
 func (c *Context) PostForm(key string) string {
 	return c.Req.FormValue(key)
 }
 
+// MultipartForm parses the multipart body, keeping up to
+// Engine.MaxMultipartMemory bytes in memory
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.Req.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
+		return nil, err
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile returns the first file uploaded under the form field name
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.Req.MultipartForm == nil {
+		if _, err := c.MultipartForm(); err != nil {
+			return nil, err
+		}
+	}
+	f, fh, err := c.Req.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fh, nil
+}
+
+// SaveUploadedFile copies an uploaded file to dst, creating its directory
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
 func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
 }
 
+// QueryDefault returns the query value of key, def if key is absent.
+// An empty ?key= is present and returns ""
+func (c *Context) QueryDefault(key, def string) string {
+	if values, ok := c.Req.URL.Query()[key]; ok {
+		return values[0]
+	}
+	return def
+}
+
+// QueryArray returns all query values of key, e.g. ?id=1&id=2
+func (c *Context) QueryArray(key string) []string {
+	return c.Req.URL.Query()[key]
+}
+
+// QueryMap returns the query values of the form key[sub]=value as a map,
+// e.g. ?ids[a]=1&ids[b]=2 gives {"a": "1", "b": "2"} for QueryMap("ids")
+func (c *Context) QueryMap(key string) map[string]string {
+	dict := make(map[string]string)
+	for k, values := range c.Req.URL.Query() {
+		if len(k) > len(key)+2 && strings.HasPrefix(k, key+"[") && k[len(k)-1] == ']' {
+			dict[k[len(key)+1:len(k)-1]] = values[0]
+		}
+	}
+	return dict
+}
+
+// Cookie returns the unescaped value of the named request cookie,
+// http.ErrNoCookie if there is none
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+// SetSameSite sets the SameSite attribute of the cookies set afterwards
+// with SetCookie
+func (c *Context) SetSameSite(sameSite http.SameSite) {
+	c.sameSite = sameSite
+}
+
+// SetCookie adds a Set-Cookie header, value is escaped. maxAge is in
+// seconds, 0 makes a session cookie and a negative value deletes it
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// Status sets the response status, it is sent with the first body write
+// (see ResponseWriter) and can be read back with c.Writer.Status()
 func (c *Context) Status(code int) {
-	c.StatusCode = code
 	c.Writer.WriteHeader(code)
 }
 
@@ -65,35 +314,236 @@ func (c *Context) SetHeader(key, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
-func (c *Context) String(code int, format string, values ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// bodyAllowedForStatus reports whether a response with status may have a body
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// Render encodes the body with r before anything is written, so an
+// encoding error still becomes a clean 500 instead of a half-written response
+func (c *Context) Render(code int, r render.Render) {
+	if !bodyAllowedForStatus(code) {
+		c.Status(code)
+		return
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+
+	if err := r.Render(buf); err != nil {
+		c.Error(err).SetType(ErrorTypeRender)
+		c.SetHeader("Content-Type", MIMEPlain+"; charset=utf-8")
+		c.Status(http.StatusInternalServerError)
+		fmt.Fprintf(c.Writer, "500 INTERNAL SERVER ERROR: %s\n", err)
+		return
+	}
+	if contentType := r.ContentType(); contentType != "" {
+		c.SetHeader("Content-Type", contentType)
+	}
 	c.Status(code)
-	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
+	c.Writer.Write(buf.Bytes())
+}
+
+func (c *Context) String(code int, format string, values ...interface{}) {
+	c.Render(code, render.Text{Format: format, Data: values})
 }
 
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Context-Type", "application/json")
-	c.Status(code)
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.Writer, err.Error(), 500)
+	c.Render(code, render.JSON{Data: obj})
+}
+
+// IndentedJSON renders obj as human readable JSON, mostly for debugging
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, render.IndentedJSON{Data: obj})
+}
+
+// SecureJSON prefixes JSON arrays with "while(1);" against JSON hijacking
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Render(code, render.SecureJSON{Prefix: "while(1);", Data: obj})
+}
+
+// PureJSON renders obj as JSON without escaping HTML characters such as <
+func (c *Context) PureJSON(code int, obj interface{}) {
+	c.Render(code, render.PureJSON{Data: obj})
+}
+
+// JSONP wraps the JSON of obj in the function named by the "callback" query,
+// a callback that isn't a JavaScript identifier is refused with 400
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback != "" && !render.ValidCallback(callback) {
+		c.Fail(http.StatusBadRequest, render.ErrInvalidCallback.Error())
+		return
 	}
+	c.Render(code, render.JSONP{Callback: callback, Data: obj})
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, render.XML{Data: obj})
+}
+
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, render.YAML{Data: obj})
+}
+
+func (c *Context) ProtoBuf(code int, obj proto.Message) {
+	c.Render(code, render.ProtoBuf{Data: obj})
 }
 
 func (c *Context) Data(code int, data []byte) {
+	c.Render(code, render.Data{Data: data})
+}
+
+// DataFromReader streams reader to the client without buffering it,
+// contentLength is -1 when unknown
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	r := render.Reader{MIME: contentType, ContentLength: contentLength, Headers: extraHeaders, Reader: reader}
+	for key, value := range r.Headers {
+		c.SetHeader(key, value)
+	}
+	if r.ContentLength >= 0 {
+		c.SetHeader("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	if r.MIME != "" {
+		c.SetHeader("Content-Type", r.MIME)
+	}
 	c.Status(code)
-	c.Writer.Write(data)
+	r.Render(c.Writer)
 }
 
+// HTML renders the template name with the templates of the route group,
+// or of its closest parent that has templates, see LoadHTMLGlob
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(http.StatusInternalServerError, err.Error())
-	}
+	c.Render(code, c.htmlRender().Instance(name, data))
 }
 
+// Fail aborts the chain and responds code with {"message": err}
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers)
-	c.JSON(code, H{"message": err})
+	c.AbortWithStatusJSON(code, H{"message": err})
+}
+
+// Set stores value under key for the rest of the request,
+// e.g. an authenticated user set by a middleware for the handlers
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+// Get returns the value stored under key and whether it exists
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, exists = c.Keys[key]
+	return
+}
+
+// MustGet returns the value stored under key, it panics if there is none
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("gee: key \"" + key + "\" does not exist")
+}
+
+// GetString returns the value of key as a string, "" if absent or not a string
+func (c *Context) GetString(key string) (s string) {
+	if value, ok := c.Get(key); ok && value != nil {
+		s, _ = value.(string)
+	}
+	return
+}
+
+// GetBool returns the value of key as a bool
+func (c *Context) GetBool(key string) (b bool) {
+	if value, ok := c.Get(key); ok && value != nil {
+		b, _ = value.(bool)
+	}
+	return
+}
+
+// GetInt returns the value of key as an int
+func (c *Context) GetInt(key string) (i int) {
+	if value, ok := c.Get(key); ok && value != nil {
+		i, _ = value.(int)
+	}
+	return
+}
+
+// GetInt64 returns the value of key as an int64
+func (c *Context) GetInt64(key string) (i int64) {
+	if value, ok := c.Get(key); ok && value != nil {
+		i, _ = value.(int64)
+	}
+	return
+}
+
+// GetFloat64 returns the value of key as a float64
+func (c *Context) GetFloat64(key string) (f float64) {
+	if value, ok := c.Get(key); ok && value != nil {
+		f, _ = value.(float64)
+	}
+	return
+}
+
+// GetDuration returns the value of key as a time.Duration
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	if value, ok := c.Get(key); ok && value != nil {
+		d, _ = value.(time.Duration)
+	}
+	return
+}
+
+// Deadline, Done, Err and Value implement context.Context by delegating to
+// c.Req.Context(), so c can be passed to anything expecting a context
+
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Req == nil {
+		return
+	}
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Err()
+}
+
+// Value returns the value stored with Set for string keys,
+// falling back to the request context
+func (c *Context) Value(key interface{}) interface{} {
+	if s, ok := key.(string); ok {
+		if value, exists := c.Get(s); exists {
+			return value
+		}
+	}
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Value(key)
 }
@@ -0,0 +1,369 @@
+package gee
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestFormFileUpload(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "imports", "users.csv")
+
+	r := New()
+	r.POST("/upload", func(ctx *Context) {
+		fh, err := ctx.FormFile("users")
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err = ctx.SaveUploadedFile(fh, dst); err != nil {
+			ctx.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		form, _ := ctx.MultipartForm()
+		ctx.String(http.StatusOK, "%s %s %d", form.Value["owner"][0], fh.Filename, fh.Size)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, map[string]string{"owner": "geektutu"}, map[string]string{"users": "id,name\n1,gee\n"}))
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu users.csv 14" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if content, err := ioutil.ReadFile(dst); err != nil || string(content) != "id,name\n1,gee\n" {
+		t.Fatalf("uploaded file not saved: %q, %v", content, err)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, nil, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("missing file should fail, got status %d", w.Code)
+	}
+}
+
+func TestMaxMultipartMemory(t *testing.T) {
+	r := New()
+	r.MaxMultipartMemory = 1
+	r.POST("/upload", func(ctx *Context) {
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.String(http.StatusOK, "%d", len(form.File["avatar"]))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, nil, map[string]string{"avatar": "large enough to spill to disk"}))
+	if w.Code != http.StatusOK || w.Body.String() != "1" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRenderEncodingError(t *testing.T) {
+	r := New()
+	r.GET("/json", func(ctx *Context) {
+		ctx.JSON(http.StatusOK, H{"ch": make(chan int)})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/json", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	r := New()
+	r.GET("/", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{MIMEJSON, MIMEXML, MIMEYAML},
+			Data:    H{"name": "gee"},
+		})
+	})
+
+	cases := []struct {
+		accept      string
+		code        int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json; charset=utf-8"},
+		{"application/xml", http.StatusOK, "application/xml; charset=utf-8"},
+		{"text/html;q=0.9, application/x-yaml", http.StatusOK, "application/x-yaml; charset=utf-8"},
+		{"application/json;q=0.5, application/*;q=0.8", http.StatusOK, "application/json; charset=utf-8"},
+		{"text/html", http.StatusNotAcceptable, "application/json; charset=utf-8"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code || w.Header().Get("Content-Type") != c.contentType {
+			t.Errorf("Accept %q: expected %d %q, got %d %q", c.accept, c.code, c.contentType, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestNegotiateTextAndProtoBuf(t *testing.T) {
+	r := New()
+	r.GET("/", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, Negotiate{
+			Offered:      []string{MIMEJSON, MIMEPlain, MIMEProtoBuf},
+			TextData:     "gee",
+			ProtoBufData: wrapperspb.String("gee"),
+			Data:         H{"name": "gee"},
+		})
+	})
+	r.GET("/unsupported", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEJSON, "image/png"}, Data: "gee"})
+	})
+
+	cases := []struct {
+		path, accept string
+		code         int
+		contentType  string
+	}{
+		{"/", "text/plain", http.StatusOK, "text/plain; charset=utf-8"},
+		{"/", "application/x-protobuf", http.StatusOK, "application/x-protobuf"},
+		{"/unsupported", "image/png", http.StatusInternalServerError, "application/json; charset=utf-8"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.path, nil)
+		req.Header.Set("Accept", c.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code || w.Header().Get("Content-Type") != c.contentType {
+			t.Errorf("%s Accept %q: expected %d %q, got %d %q", c.path, c.accept, c.code, c.contentType, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "gee" {
+		t.Errorf("expected the text body, got %q", w.Body.String())
+	}
+}
+
+func TestJSONPCallback(t *testing.T) {
+	r := New()
+	r.GET("/", func(ctx *Context) {
+		ctx.JSONP(http.StatusOK, H{"name": "gee"})
+	})
+
+	cases := []struct {
+		query, body, contentType string
+		code                     int
+	}{
+		{"?callback=cb", `cb({"name":"gee"});`, "application/javascript; charset=utf-8", http.StatusOK},
+		{"", `{"name":"gee"}`, "application/json; charset=utf-8", http.StatusOK},
+		{"?callback=alert(document.domain)%3Bf", `{"message":"render: invalid JSONP callback"}`,
+			"application/json; charset=utf-8", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/"+c.query, nil))
+		if w.Code != c.code || strings.TrimSpace(w.Body.String()) != c.body || w.Header().Get("Content-Type") != c.contentType {
+			t.Errorf("%q: expected %d %q %q, got %d %q %q", c.query, c.code, c.contentType, c.body,
+				w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
+
+func TestContextKeysArePerRequest(t *testing.T) {
+	r := New()
+	r.Use(func(ctx *Context) {
+		if _, exists := ctx.Get("user"); exists {
+			t.Error("keys leaked from a previous request")
+		}
+		ctx.Set("user", ctx.Query("user"))
+		ctx.Set("uid", 7)
+		ctx.Next()
+	})
+	r.GET("/", func(ctx *Context) {
+		ctx.String(http.StatusOK, "%s %d %s", ctx.GetString("user"), ctx.GetInt("uid"), ctx.Value("user"))
+	})
+
+	for _, user := range []string{"geektutu", "gee"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/?user="+user, nil))
+		if expected := user + " 7 " + user; w.Body.String() != expected {
+			t.Fatalf("expected %q, got %q", expected, w.Body.String())
+		}
+	}
+}
+
+func TestContextParamsAreReset(t *testing.T) {
+	r := New()
+	r.GET("/user/:id", func(ctx *Context) {
+		ctx.String(http.StatusOK, ctx.Param("id"))
+	})
+	r.GET("/about", func(ctx *Context) {
+		ctx.String(http.StatusOK, "%d", len(ctx.Params))
+	})
+
+	for _, path := range []string{"/user/1", "/about"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if path == "/about" && w.Body.String() != "0" {
+			t.Fatalf("params leaked from a previous request: %q", w.Body.String())
+		}
+	}
+}
+
+func TestContextTypedParams(t *testing.T) {
+	r := New()
+	r.GET("/n/:id", func(ctx *Context) {
+		id, err := ctx.ParamInt("id")
+		ctx.String(http.StatusOK, "%d %v", id, err != nil)
+	})
+	r.GET("/u/:id", func(ctx *Context) {
+		id, err := ctx.ParamUUID("id")
+		ctx.String(http.StatusOK, "%s %v", id, err != nil)
+	})
+
+	cases := map[string]string{
+		"/n/42":  "42 false",
+		"/n/abc": "0 true",
+		"/u/3F2504E0-4F89-11D3-9A0C-0305E82C3301": "3f2504e0-4f89-11d3-9a0c-0305e82c3301 false",
+		"/u/3F2504E04F8911D39A0C0305E82C3301":     " true",
+	}
+	for path, expected := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, w.Body.String())
+		}
+	}
+}
+
+func TestContextQueryHelpers(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?page=&id=1&id=2&ids[a]=x&ids[b]=y&ids=z&idsx[c]=w", nil)
+	ctx := New().acquireContext(httptest.NewRecorder(), req)
+
+	if got := ctx.QueryDefault("page", "1"); got != "" {
+		t.Errorf("an empty page should stay empty, got %q", got)
+	}
+	if got := ctx.QueryDefault("size", "20"); got != "20" {
+		t.Errorf("expected the default size, got %q", got)
+	}
+	if got := ctx.QueryArray("id"); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("expected both ids, got %v", got)
+	}
+	if got := ctx.QueryArray("missing"); len(got) != 0 {
+		t.Errorf("expected no values, got %v", got)
+	}
+	if got := ctx.QueryMap("ids"); !reflect.DeepEqual(got, map[string]string{"a": "x", "b": "y"}) {
+		t.Errorf("expected the ids map, got %v", got)
+	}
+}
+
+func TestContextFullPath(t *testing.T) {
+	r := New()
+	r.Use(func(ctx *Context) {
+		ctx.Next()
+		ctx.SetHeader("X-Full-Path", ctx.FullPath())
+	})
+	r.GET("/user/:id/*filepath", func(ctx *Context) {})
+
+	cases := map[string]string{"/user/1/a/b": "/user/:id/*filepath", "/missing": ""}
+	for path, expected := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if got := w.Header().Get("X-Full-Path"); got != expected {
+			t.Errorf("%s: expected full path %q, got %q", path, expected, got)
+		}
+	}
+}
+
+func TestContextCookies(t *testing.T) {
+	r := New()
+	r.GET("/", func(ctx *Context) {
+		lang, err := ctx.Cookie("lang")
+		if err != nil {
+			lang = "none"
+		}
+		ctx.SetSameSite(http.SameSiteStrictMode)
+		ctx.SetCookie("seen", "a b;c", 3600, "", "geektutu.com", true, true)
+		ctx.String(http.StatusOK, lang)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "zh%20CN"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "zh CN" {
+		t.Fatalf("expected the unescaped cookie, got %q", w.Body.String())
+	}
+	expected := "seen=a+b%3Bc; Path=/; Domain=geektutu.com; Max-Age=3600; HttpOnly; Secure; SameSite=Strict"
+	if got := w.Header().Get("Set-Cookie"); got != expected {
+		t.Fatalf("expected Set-Cookie %q, got %q", expected, got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "none" {
+		t.Fatalf("expected no cookie, got %q", w.Body.String())
+	}
+}
+
+func TestContextImplementsContext(t *testing.T) {
+	type ctxKey struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "from request"))
+	c := New().acquireContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(parent))
+
+	if c.Value(ctxKey{}) != "from request" {
+		t.Fatal("Value should fall back to the request context")
+	}
+	cancel()
+	select {
+	case <-c.Done():
+	default:
+		t.Fatal("Done should follow the request context")
+	}
+	if c.Err() != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", c.Err())
+	}
+
+	cp := c.Copy()
+	c.Set("user", "gee")
+	if _, exists := cp.Get("user"); exists {
+		t.Fatal("a copy shouldn't share keys with the original")
+	}
+}
@@ -0,0 +1,163 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorType classifies the errors collected with Context.Error
+type ErrorType uint64
+
+const (
+	// ErrorTypeBind is used when Context.Bind fails
+	ErrorTypeBind ErrorType = 1 << 63
+	// ErrorTypeRender is used when a response body fails to encode
+	ErrorTypeRender ErrorType = 1 << 62
+	// ErrorTypePrivate errors are logged but never shown to the client
+	ErrorTypePrivate ErrorType = 1 << 0
+	// ErrorTypePublic errors may be shown to the client
+	ErrorTypePublic ErrorType = 1 << 1
+	// ErrorTypeAny matches every type
+	ErrorTypeAny ErrorType = 1<<64 - 1
+)
+
+// Error is an error collected during a request with Context.Error
+type Error struct {
+	Err  error
+	Type ErrorType
+	Meta interface{}
+}
+
+var _ error = &Error{}
+
+func (msg *Error) Error() string {
+	return msg.Err.Error()
+}
+
+func (msg *Error) Unwrap() error {
+	return msg.Err
+}
+
+// SetType changes the type of msg, private by default
+func (msg *Error) SetType(flags ErrorType) *Error {
+	msg.Type = flags
+	return msg
+}
+
+// SetMeta attaches data rendered along with the error
+func (msg *Error) SetMeta(data interface{}) *Error {
+	msg.Meta = data
+	return msg
+}
+
+// IsType reports whether msg has one of the types in flags
+func (msg *Error) IsType(flags ErrorType) bool {
+	return msg.Type&flags > 0
+}
+
+// JSON returns the client facing form of msg
+func (msg *Error) JSON() interface{} {
+	h := H{"message": msg.Error()}
+	if fields, ok := msg.Err.(ValidationErrors); ok {
+		h["fields"] = fields
+	}
+	switch meta := msg.Meta.(type) {
+	case nil:
+	case H:
+		for key, value := range meta {
+			h[key] = value
+		}
+	default:
+		h["meta"] = meta
+	}
+	return h
+}
+
+type errorMsgs []*Error
+
+// ByType returns the errors with one of the types in flags
+func (a errorMsgs) ByType(flags ErrorType) errorMsgs {
+	if flags == ErrorTypeAny {
+		return a
+	}
+	var result errorMsgs
+	for _, msg := range a {
+		if msg.IsType(flags) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// Last returns the most recent error, nil if there is none
+func (a errorMsgs) Last() *Error {
+	if len(a) == 0 {
+		return nil
+	}
+	return a[len(a)-1]
+}
+
+// Errors returns the messages of all errors
+func (a errorMsgs) Errors() []string {
+	messages := make([]string, len(a))
+	for i, msg := range a {
+		messages[i] = msg.Error()
+	}
+	return messages
+}
+
+func (a errorMsgs) String() string {
+	var buf strings.Builder
+	for i, msg := range a {
+		fmt.Fprintf(&buf, "Error #%02d: %s\n", i+1, msg.Err)
+		if msg.Meta != nil {
+			fmt.Fprintf(&buf, "     Meta: %v\n", msg.Meta)
+		}
+	}
+	return buf.String()
+}
+
+// Error records err for the error handling middlewares and returns it as
+// an *Error, private by default. It doesn't stop the chain
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("gee: err is nil")
+	}
+
+	parsed, ok := err.(*Error)
+	if !ok {
+		parsed = &Error{Err: err, Type: ErrorTypePrivate}
+	}
+	c.Errors = append(c.Errors, parsed)
+	return parsed
+}
+
+// ErrorHandler renders the errors collected with Context.Error once the
+// rest of the chain returns without having written a response. Public and
+// bind errors are shown to the client, the others only as the status text
+func ErrorHandler() HandlerFunc {
+	return func(ctx *Context) {
+		ctx.Next()
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		code := ctx.Writer.Status()
+		if code < http.StatusBadRequest {
+			code = http.StatusInternalServerError
+			if ctx.Errors.Last().IsType(ErrorTypeBind) {
+				code = http.StatusBadRequest
+			}
+		}
+
+		var errs []interface{}
+		for _, msg := range ctx.Errors.ByType(ErrorTypePublic | ErrorTypeBind) {
+			errs = append(errs, msg.JSON())
+		}
+		if len(errs) == 0 {
+			errs = append(errs, H{"message": http.StatusText(code)})
+		}
+		ctx.JSON(code, H{"errors": errs})
+	}
+}
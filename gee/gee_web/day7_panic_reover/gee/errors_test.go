@@ -0,0 +1,91 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAbortStopsTheChain(t *testing.T) {
+	r := New()
+	auth := r.Group("/admin")
+	auth.Use(func(ctx *Context) {
+		if ctx.Req.Header.Get("Authorization") == "" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	auth.GET("/", func(ctx *Context) {
+		ctx.String(http.StatusOK, "welcome")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/admin/", nil))
+	if w.Code != http.StatusUnauthorized || w.Body.Len() != 0 {
+		t.Fatalf("expected a bare 401, got %d %q", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/admin/", nil)
+	req.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "welcome" {
+		t.Fatalf("expected welcome, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	r := New()
+	r.Use(ErrorHandler())
+	r.GET("/private", func(ctx *Context) {
+		ctx.Error(errors.New("db password leaked"))
+	})
+	r.GET("/public", func(ctx *Context) {
+		ctx.AbortWithError(http.StatusForbidden, errors.New("quota exceeded")).
+			SetType(ErrorTypePublic).SetMeta(H{"limit": 10})
+	})
+	r.POST("/bind", func(ctx *Context) {
+		var u bindUser
+		ctx.Bind(&u)
+	})
+
+	cases := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/private", http.StatusInternalServerError, `{"errors":[{"message":"Internal Server Error"}]}`},
+		{"GET", "/public", http.StatusForbidden, `{"errors":[{"limit":10,"message":"quota exceeded"}]}`},
+		{"POST", "/bind", http.StatusBadRequest, `"fields":[{"field":"ID","rule":"required"}`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(`{"name":"gee"}`))
+		req.Header.Set("Content-Type", MIMEJSON)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code || !strings.Contains(w.Body.String(), c.body) {
+			t.Errorf("%s: expected %d %s, got %d %s", c.path, c.code, c.body, w.Code, w.Body.String())
+		}
+		if !json.Valid(w.Body.Bytes()) {
+			t.Errorf("%s: invalid JSON body %s", c.path, w.Body.String())
+		}
+	}
+}
+
+func TestErrorMsgs(t *testing.T) {
+	c := New().acquireContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.Error(errors.New("private"))
+	c.Error(&Error{Err: errors.New("public"), Type: ErrorTypePublic})
+
+	if public := c.Errors.ByType(ErrorTypePublic); len(public) != 1 || public[0].Error() != "public" {
+		t.Fatalf("unexpected public errors %v", public)
+	}
+	if c.Errors.Last().Error() != "public" || len(c.Errors.Errors()) != 2 {
+		t.Fatalf("unexpected errors %v", c.Errors.Errors())
+	}
+	if !c.Errors.Last().IsType(ErrorTypeAny) {
+		t.Fatal("every error matches ErrorTypeAny")
+	}
+}
@@ -1,18 +1,22 @@
 package gee
 
 import (
+	"gee/render"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
-	"path"
 	"strings"
+	"sync"
+	"time"
 )
 
 type RouterGroup struct {
 	prefix      string
-	middlewares []HandlerFunc // support middleware 支持中间件
-	parent      *RouterGroup  // support nesting 支持嵌套
-	engine      *Engine       // all groups share a Engine instance 所有分组共享一个engine实例
+	middlewares []HandlerFunc     // support middleware 支持中间件
+	parent      *RouterGroup      // support nesting 支持嵌套
+	engine      *Engine           // all groups share a Engine instance 所有分组共享一个engine实例
+	htmlRender  render.HTMLRender // templates of the group, see LoadHTMLGlob
 }
 
 // HandlerFunc defines the request handler used by gee （定义处理方法）
@@ -21,17 +25,57 @@ type HandlerFunc func(ctx *Context)
 // Engine implement the interface of ServeHTTP （实现对应接口）
 type Engine struct {
 	*RouterGroup
-	router        *router
-	gropus        []*RouterGroup     // store all groups
-	htmlTemplates *template.Template // for html render
-	funcMap       template.FuncMap   // for html render
+	router  *router
+	gropus  []*RouterGroup   // store all groups
+	funcMap template.FuncMap // for html render
+	routes  []*Route         // in registration order, see Routes
+	named   map[string]*Route
+	// proxies whose forwarding headers are believed, see SetTrustedProxies
+	trustedCIDRs []*net.IPNet
+	noRoute      []HandlerFunc
+	noMethod     []HandlerFunc
+
+	// RedirectTrailingSlash redirects /foo/ to /foo, or the opposite, when
+	// only the other one has a route. On by default
+	RedirectTrailingSlash bool
+	// RedirectFixedPath redirects requests with superfluous elements such
+	// as ../ or //, or with the wrong case, to the route they match
+	RedirectFixedPath bool
+	pool              sync.Pool // recycles *Context between requests
+
+	// MaxMultipartMemory is the number of bytes of a multipart body kept in
+	// memory, the remaining file parts are stored on disk
+	MaxMultipartMemory int64
+
+	// HTMLDebug parses the templates again on each render, so edits show
+	// up without a restart. Keep it off in production
+	HTMLDebug bool
+
+	// timeouts of the http.Server started by the Run methods, zero means
+	// no timeout. They must be set before the first Run or Server call
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	serverOnce sync.Once
+	server     *http.Server
 }
 
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
 // New is the constructor of gee.Engine (构造方法)
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		router:                newRouter(),
+		MaxMultipartMemory:    defaultMultipartMemory,
+		RedirectTrailingSlash: true,
+	}
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.gropus = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return &Context{engine: engine, Params: make(map[string]string)}
+	}
 	return engine
 }
 
@@ -55,73 +99,143 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	return newGroup
 }
 
-func (group *RouterGroup) addRoute(method, comp string, handler HandlerFunc) {
+// combineHandlers resolves the middleware chain of group, from the outermost
+// parent down to group itself, and appends handlers to it
+func (group *RouterGroup) combineHandlers(handlers ...HandlerFunc) []HandlerFunc {
+	var groups []*RouterGroup
+	for g := group; g != nil; g = g.parent {
+		groups = append(groups, g)
+	}
+
+	var merged []HandlerFunc
+	for i := len(groups) - 1; i >= 0; i-- {
+		merged = append(merged, groups[i].middlewares...)
+	}
+	return append(merged, handlers...)
+}
+
+// addRoute resolves the middleware chain once, at registration time, so only
+// the groups that own the route run and ServeHTTP needs a single lookup
+// handlers are route specific middlewares followed by the route handler
+func (group *RouterGroup) addRoute(method, comp string, handlers []HandlerFunc) *Route {
 	pattern := group.prefix + comp
+	if len(handlers) == 0 {
+		panic("gee: route " + method + " " + pattern + " needs at least one handler")
+	}
 	log.Printf("Route %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	n := group.engine.router.addRoute(method, pattern, group.combineHandlers(handlers...))
+	n.group = group
+
+	route := &Route{Method: method, Path: pattern, node: n, engine: group.engine}
+	group.engine.routes = append(group.engine.routes, route)
+	return route
+}
+
+// anyMethods are the methods registered by Any
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+// Handle registers a handler for an arbitrary HTTP method, the route
+// methods below all accept route specific middlewares before the handler
+func (group *RouterGroup) Handle(method, pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(method, pattern, handlers)
 }
 
 // GET defines the method to add GET request (定义GET方法)
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+// a GET route also answers HEAD requests unless HEAD is registered explicitly
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodGet, pattern, handlers)
 }
 
 // POST defines the method to add POST request (定义POST方法)
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodPost, pattern, handlers)
 }
 
-func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
-	group.middlewares = append(group.middlewares, middlewares...)
+// PUT defines the method to add PUT request
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodPut, pattern, handlers)
 }
 
-// create static handler
-func (group RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	absolutePath := path.Join(group.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(ctx *Context) {
-		file := ctx.Param("filepath")
-		// check if file exists and/or if we have permission to access it
-		if _, err := fs.Open(file); err != nil {
-			ctx.Status(http.StatusNotFound)
-			return
-		}
+// PATCH defines the method to add PATCH request
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodPatch, pattern, handlers)
+}
+
+// DELETE defines the method to add DELETE request
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodDelete, pattern, handlers)
+}
+
+// HEAD defines the method to add HEAD request
+func (group *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodHead, pattern, handlers)
+}
+
+// OPTIONS defines the method to add OPTIONS request
+// without it the router answers OPTIONS with an Allow header on its own
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodOptions, pattern, handlers)
+}
 
-		fileServer.ServeHTTP(ctx.Writer, ctx.Req)
+// Any registers the handlers for all common HTTP methods and returns the
+// routes. They share the path, so naming any one of them is enough for URL
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) []*Route {
+	routes := make([]*Route, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		routes = append(routes, group.addRoute(method, pattern, handlers))
 	}
+	return routes
 }
 
-// serve tstaic files
-func (group *RouterGroup) Static(relativePath string, root string) {
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	urlPattern := path.Join(relativePath, "/*filepath")
-	// Register GET handlers
-	group.GET(urlPattern, handler)
+// NoRoute sets the handlers of requests matching no route, 404 text by
+// default. They run after the middlewares of the group owning the path
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.noRoute = handlers
 }
 
-// Run defines the method to start a http server （定义http启动方法)）
-func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+// NoMethod sets the handlers of requests whose path only has routes for
+// other methods, 405 text by default. The Allow header is already set
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.noMethod = handlers
 }
 
-func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandlerFunc
+// groupFor returns the group with the longest prefix owning p
+func (engine *Engine) groupFor(p string) *RouterGroup {
+	owner := engine.RouterGroup
 	for _, group := range engine.gropus {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
+		prefix := strings.TrimSuffix(group.prefix, "/")
+		if len(prefix) <= len(strings.TrimSuffix(owner.prefix, "/")) {
+			continue
+		}
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			owner = group
 		}
 	}
+	return owner
+}
 
-	c := newContext(w, req)
-	c.handlers = middlewares
-	c.engine = engine
-	engine.router.handle(c)
+// Use adds middlewares to the group, they apply to routes registered afterwards
+func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
+	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
-	engine.funcMap = funcMap
+func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c := engine.acquireContext(w, req)
+	engine.router.handle(c)
+	// send the status of handlers that never wrote a body
+	c.Writer.WriteHeaderNow()
+	engine.pool.Put(c)
 }
 
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+// acquireContext takes a Context from the pool and prepares it for req
+func (engine *Engine) acquireContext(w http.ResponseWriter, req *http.Request) *Context {
+	c := engine.pool.Get().(*Context)
+	c.writermem.reset(w)
+	c.Req = req
+	c.reset()
+	return c
 }
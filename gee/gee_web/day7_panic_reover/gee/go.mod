@@ -1,3 +1,8 @@
 module gee
 
-go 1.15
+go 1.16
+
+require (
+	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v2 v2.4.0
+)
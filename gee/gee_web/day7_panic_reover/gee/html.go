@@ -0,0 +1,150 @@
+package gee
+
+import (
+	"fmt"
+	"gee/render"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// SetFuncMap adds the functions of funcMap to the HTML templates, it must
+// be called before the templates are loaded. Functions added by
+// middlewares with AddFuncMap are kept
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	for name, fn := range funcMap {
+		engine.AddFuncMap(name, fn)
+	}
+}
+
+// AddFuncMap adds one function to the HTML templates, e.g. the csrfField
+// of middleware.CSRF. It must be called before the templates are loaded
+func (engine *Engine) AddFuncMap(name string, fn interface{}) {
+	if engine.funcMap == nil {
+		engine.funcMap = make(template.FuncMap)
+	}
+	engine.funcMap[name] = fn
+}
+
+// SetHTMLRender sets the templates of the group and its subgroups,
+// e.g. another template engine. On the Engine it sets the default ones
+func (group *RouterGroup) SetHTMLRender(r render.HTMLRender) {
+	group.htmlRender = r
+}
+
+// LoadHTMLGlob parses the files matching pattern as the templates of the
+// group, it panics when they can't be parsed
+func (group *RouterGroup) LoadHTMLGlob(pattern string) {
+	group.loadHTML(func(funcMap template.FuncMap) (render.HTMLRender, error) {
+		t, err := template.New("").Funcs(funcMap).ParseGlob(pattern)
+		return render.HTMLProduction{Template: t}, err
+	})
+}
+
+// LoadHTMLFS is LoadHTMLGlob reading fsys, e.g. an embed.FS or os.DirFS
+func (group *RouterGroup) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	group.loadHTML(func(funcMap template.FuncMap) (render.HTMLRender, error) {
+		t, err := template.New("").Funcs(funcMap).ParseFS(fsys, patterns...)
+		return render.HTMLProduction{Template: t}, err
+	})
+}
+
+// LoadHTMLLayouts parses each file matching pages in a template set of its
+// own, together with the layouts and partials matching shared, and names
+// it after the file. Pages can then fill the {{block}}s of a layout:
+//
+//	layouts/base.tmpl:  <html>{{block "content" .}}{{end}}</html>
+//	pages/index.tmpl:   {{template "base.tmpl" .}}{{define "content"}}...{{end}}
+//
+//	r.LoadHTMLLayouts(os.DirFS("templates"), []string{"layouts/*"}, "pages/*")
+//	ctx.HTML(http.StatusOK, "index.tmpl", data)
+//
+// Every page picks its layout, so several base templates can coexist
+func (group *RouterGroup) LoadHTMLLayouts(fsys fs.FS, shared []string, pages ...string) {
+	group.loadHTML(func(funcMap template.FuncMap) (render.HTMLRender, error) {
+		sets := make(render.HTMLPages)
+		for _, pattern := range pages {
+			files, err := fs.Glob(fsys, pattern)
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == 0 {
+				return nil, fmt.Errorf("gee: pattern matches no files: %#q", pattern)
+			}
+			for _, file := range files {
+				name := path.Base(file)
+				if _, ok := sets[name]; ok {
+					return nil, fmt.Errorf("gee: html page %s is defined twice", name)
+				}
+				// layouts first, so the blocks defined by the page win
+				t := template.New(name).Funcs(funcMap)
+				if len(shared) > 0 {
+					if t, err = t.ParseFS(fsys, shared...); err != nil {
+						return nil, err
+					}
+				}
+				if t, err = t.ParseFS(fsys, file); err != nil {
+					return nil, err
+				}
+				sets[name] = t
+			}
+		}
+		return sets, nil
+	})
+}
+
+// htmlLoader parses the templates once, or again on each render when
+// Engine.HTMLDebug is set
+type htmlLoader struct {
+	engine *Engine
+	load   func(funcMap template.FuncMap) (render.HTMLRender, error)
+	parsed render.HTMLRender
+}
+
+func (group *RouterGroup) loadHTML(load func(funcMap template.FuncMap) (render.HTMLRender, error)) {
+	parsed, err := load(group.engine.funcMap)
+	if err != nil {
+		panic(err)
+	}
+	group.htmlRender = &htmlLoader{engine: group.engine, load: load, parsed: parsed}
+}
+
+func (l *htmlLoader) Instance(name string, data interface{}) render.Render {
+	if !l.engine.HTMLDebug {
+		return l.parsed.Instance(name, data)
+	}
+	parsed, err := l.load(l.engine.funcMap)
+	if err != nil {
+		return htmlError{err}
+	}
+	return parsed.Instance(name, data)
+}
+
+// htmlError reports templates that failed to parse in debug mode
+type htmlError struct {
+	err error
+}
+
+func (r htmlError) Render(io.Writer) error {
+	return r.err
+}
+
+func (r htmlError) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+// htmlRender returns the templates of the route group or of its closest
+// parent that has some, the Engine ones for unmatched requests
+func (c *Context) htmlRender() render.HTMLRender {
+	group := c.group
+	if group == nil {
+		group = c.engine.RouterGroup
+	}
+	for ; group != nil; group = group.parent {
+		if group.htmlRender != nil {
+			return group.htmlRender
+		}
+	}
+	return render.HTMLProduction{}
+}
@@ -0,0 +1,132 @@
+package gee
+
+import (
+	"embed"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func renderHTML(r *Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestLoadHTMLLayouts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.tmpl":   {Data: []byte(`<main>{{block "content" .}}empty{{end}}</main>{{template "footer.tmpl"}}`)},
+		"layouts/admin.tmpl":  {Data: []byte(`<admin>{{block "content" .}}{{end}}</admin>`)},
+		"layouts/footer.tmpl": {Data: []byte(`<footer>{{upper "gee"}}</footer>`)},
+		"pages/index.tmpl":    {Data: []byte(`{{template "base.tmpl" .}}{{define "content"}}index {{.}}{{end}}`)},
+		"pages/about.tmpl":    {Data: []byte(`{{template "base.tmpl" .}}{{define "content"}}about{{end}}`)},
+		"pages/users.tmpl":    {Data: []byte(`{{template "admin.tmpl" .}}{{define "content"}}users{{end}}`)},
+	}
+
+	r := New()
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	r.LoadHTMLLayouts(fsys, []string{"layouts/*"}, "pages/*")
+	for _, page := range []string{"index", "about", "users", "missing"} {
+		page := page
+		r.GET("/"+page, func(ctx *Context) {
+			ctx.HTML(http.StatusOK, page+".tmpl", "<gee>")
+		})
+	}
+
+	cases := map[string]string{
+		"/index": "<main>index &lt;gee&gt;</main><footer>GEE</footer>",
+		"/about": "<main>about</main><footer>GEE</footer>",
+		"/users": "<admin>users</admin>",
+	}
+	for path, expected := range cases {
+		if w := renderHTML(r, path); w.Body.String() != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, w.Body.String())
+		}
+	}
+	if w := renderHTML(r, "/missing"); w.Code != http.StatusInternalServerError ||
+		!strings.Contains(w.Body.String(), `"missing.tmpl" is not loaded`) {
+		t.Errorf("expected a 500 for a missing page, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+//go:embed testdata/templates
+var embeddedTemplates embed.FS
+
+func TestLoadHTMLEmbedFS(t *testing.T) {
+	r := New()
+	r.LoadHTMLFS(embeddedTemplates, "testdata/templates/*.tmpl")
+	r.GET("/", func(ctx *Context) {
+		ctx.HTML(http.StatusOK, "index.tmpl", "embedded")
+	})
+
+	if w := renderHTML(r, "/"); strings.TrimSpace(w.Body.String()) != "<h1>embedded</h1>" {
+		t.Errorf("expected the embedded template, got %q", w.Body.String())
+	}
+}
+
+func TestGroupTemplates(t *testing.T) {
+	r := New()
+	r.LoadHTMLFS(fstest.MapFS{"index.tmpl": {Data: []byte("site")}}, "*.tmpl")
+	admin := r.Group("/admin")
+	admin.LoadHTMLFS(fstest.MapFS{"index.tmpl": {Data: []byte("admin")}}, "*.tmpl")
+	users := admin.Group("/users")
+
+	index := func(ctx *Context) {
+		ctx.HTML(http.StatusOK, "index.tmpl", nil)
+	}
+	r.GET("/", index)
+	admin.GET("/", index)
+	users.GET("/", index)
+
+	for path, expected := range map[string]string{"/": "site", "/admin/": "admin", "/admin/users/": "admin"} {
+		if w := renderHTML(r, path); w.Body.String() != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, w.Body.String())
+		}
+	}
+}
+
+func TestHTMLDebugReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.tmpl")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1")
+
+	r := New()
+	r.LoadHTMLFS(os.DirFS(dir), "*.tmpl")
+	r.GET("/", func(ctx *Context) {
+		ctx.HTML(http.StatusOK, "index.tmpl", nil)
+	})
+
+	write("v2")
+	if w := renderHTML(r, "/"); w.Body.String() != "v1" {
+		t.Fatalf("templates should be parsed once, got %q", w.Body.String())
+	}
+	r.HTMLDebug = true
+	if w := renderHTML(r, "/"); w.Body.String() != "v2" {
+		t.Fatalf("debug mode should re-parse, got %q", w.Body.String())
+	}
+	write("{{ broken")
+	if w := renderHTML(r, "/"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("a parse error should be a 500 in debug mode, got %d", w.Code)
+	}
+}
+
+func TestHTMLNotLoaded(t *testing.T) {
+	r := New()
+	r.GET("/", func(ctx *Context) {
+		ctx.HTML(http.StatusOK, "index.tmpl", nil)
+	})
+	if w := renderHTML(r, "/"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 without templates, got %d", w.Code)
+	}
+}
@@ -1,16 +1,238 @@
 package gee
 
 import (
-	"log"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
 	"time"
 )
 
+const (
+	green   = "\033[97;42m"
+	white   = "\033[90;47m"
+	yellow  = "\033[90;43m"
+	red     = "\033[97;41m"
+	blue    = "\033[97;44m"
+	magenta = "\033[97;45m"
+	cyan    = "\033[97;46m"
+	reset   = "\033[0m"
+)
+
+// HeaderRequestID carries the request ID, see the middleware package
+const HeaderRequestID = "X-Request-ID"
+
+// LogFormatter turns the params of a finished request into one log line
+type LogFormatter func(params LogFormatterParams) string
+
+// LoggerConfig configures LoggerWithConfig
+type LoggerConfig struct {
+	// Output receives the log lines, os.Stderr by default
+	Output io.Writer
+	// Formatter formats each line, it takes precedence over Template and JSON
+	Formatter LogFormatter
+	// Template is executed with LogFormatterParams for each line,
+	// e.g. {{.Method}} {{.Path}} {{.StatusCode}}
+	Template *template.Template
+	// JSON writes one JSON object per line instead of text
+	JSON bool
+	// SkipPaths are not logged, e.g. health checks
+	SkipPaths []string
+	// ForceColor colors the text output even when Output isn't a terminal,
+	// DisableColor never colors it
+	ForceColor   bool
+	DisableColor bool
+}
+
+// LogFormatterParams describes a finished request
+type LogFormatterParams struct {
+	Request      *http.Request          `json:"-"`
+	TimeStamp    time.Time              `json:"time"`
+	StatusCode   int                    `json:"status"`
+	Latency      time.Duration          `json:"latency"`
+	ClientIP     string                 `json:"client_ip"`
+	Method       string                 `json:"method"`
+	Path         string                 `json:"path"`
+	BodySize     int                    `json:"bytes"`
+	RequestID    string                 `json:"request_id,omitempty"`
+	ErrorMessage string                 `json:"error,omitempty"`
+	Keys         map[string]interface{} `json:"-"`
+
+	isTerm bool
+}
+
+// StatusCodeColor returns the ANSI color of the status code
+func (p *LogFormatterParams) StatusCodeColor() string {
+	switch code := p.StatusCode; {
+	case code >= http.StatusOK && code < http.StatusMultipleChoices:
+		return green
+	case code >= http.StatusMultipleChoices && code < http.StatusBadRequest:
+		return white
+	case code >= http.StatusBadRequest && code < http.StatusInternalServerError:
+		return yellow
+	default:
+		return red
+	}
+}
+
+// MethodColor returns the ANSI color of the method
+func (p *LogFormatterParams) MethodColor() string {
+	switch p.Method {
+	case http.MethodGet:
+		return blue
+	case http.MethodPost:
+		return cyan
+	case http.MethodPut, http.MethodPatch:
+		return yellow
+	case http.MethodDelete:
+		return red
+	case http.MethodHead:
+		return magenta
+	default:
+		return reset
+	}
+}
+
+// ResetColor returns the ANSI reset sequence
+func (p *LogFormatterParams) ResetColor() string {
+	return reset
+}
+
+// IsOutputColor reports whether the line should be colored
+func (p *LogFormatterParams) IsOutputColor() bool {
+	return p.isTerm
+}
+
+func defaultLogFormatter(p LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if p.IsOutputColor() {
+		statusColor, methodColor, resetColor = p.StatusCodeColor(), p.MethodColor(), p.ResetColor()
+	}
+	if p.Latency > time.Minute {
+		p.Latency = p.Latency.Truncate(time.Second)
+	}
+
+	line := fmt.Sprintf("[GEE] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v | %dB",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, p.StatusCode, resetColor,
+		p.Latency,
+		p.ClientIP,
+		methodColor, p.Method, resetColor,
+		p.Path,
+		p.BodySize,
+	)
+	if p.RequestID != "" {
+		line += " | " + p.RequestID
+	}
+	if p.ErrorMessage != "" {
+		line += "\n" + p.ErrorMessage
+	}
+	return line + "\n"
+}
+
+func jsonLogFormatter(p LogFormatterParams) string {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}\n", err.Error())
+	}
+	return string(b) + "\n"
+}
+
+func templateLogFormatter(tmpl *template.Template) LogFormatter {
+	return func(p LogFormatterParams) string {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, &p); err != nil {
+			return fmt.Sprintf("[GEE] log template error: %v\n", err)
+		}
+		if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+		return buf.String()
+	}
+}
+
+// isTerminal reports whether w is a character device such as a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Logger logs every request to os.Stderr with the default text format
 func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithConfig returns an access logger configured by conf
+func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
+	out := conf.Output
+	if out == nil {
+		out = os.Stderr
+	}
+
+	formatter := conf.Formatter
+	switch {
+	case formatter != nil:
+	case conf.Template != nil:
+		formatter = templateLogFormatter(conf.Template)
+	case conf.JSON:
+		formatter = jsonLogFormatter
+	default:
+		formatter = defaultLogFormatter
+	}
+
+	isTerm := !conf.DisableColor && !conf.JSON && (conf.ForceColor || isTerminal(out))
+
+	skip := make(map[string]struct{}, len(conf.SkipPaths))
+	for _, path := range conf.SkipPaths {
+		skip[path] = struct{}{}
+	}
+
 	return func(ctx *Context) {
 		t := time.Now()
+		path := ctx.Req.URL.Path
+		raw := ctx.Req.URL.RawQuery
+
 		// process request
 		ctx.Next()
+
+		if _, ok := skip[path]; ok {
+			return
+		}
+		if raw != "" {
+			path = path + "?" + raw
+		}
+
+		requestID := ctx.Writer.Header().Get(HeaderRequestID)
+		if requestID == "" {
+			requestID = ctx.Req.Header.Get(HeaderRequestID)
+		}
+		params := LogFormatterParams{
+			Request:      ctx.Req,
+			TimeStamp:    time.Now(),
+			StatusCode:   ctx.Writer.Status(),
+			ClientIP:     ctx.ClientIP(),
+			Method:       ctx.Req.Method,
+			Path:         path,
+			BodySize:     ctx.Writer.Size(),
+			RequestID:    requestID,
+			ErrorMessage: strings.TrimSuffix(ctx.Errors.ByType(ErrorTypePrivate).String(), "\n"),
+			Keys:         ctx.Keys,
+			isTerm:       isTerm,
+		}
 		// calculate resolution time
-		log.Printf("[%d] %s in %v", ctx.StatusCode, ctx.Req.RequestURI, time.Since(t))
+		params.Latency = params.TimeStamp.Sub(t)
+		if params.BodySize < 0 {
+			params.BodySize = 0
+		}
+
+		fmt.Fprint(out, formatter(params))
 	}
 }
@@ -0,0 +1,93 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func newLoggerTestEngine(conf LoggerConfig) *Engine {
+	r := New()
+	r.Use(LoggerWithConfig(conf))
+	r.GET("/user/:id", func(ctx *Context) {
+		ctx.Writer.Header().Set(HeaderRequestID, "req-1")
+		ctx.Writer.WriteString("hello")
+	})
+	r.GET("/healthz", func(ctx *Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	r.GET("/fail", func(ctx *Context) {
+		ctx.Error(errors.New("db is down"))
+		ctx.AbortWithStatus(http.StatusServiceUnavailable)
+	})
+	return r
+}
+
+func serveLoggerRequests(r *Engine, paths ...string) {
+	for _, path := range paths {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestLoggerText(t *testing.T) {
+	var buf bytes.Buffer
+	r := newLoggerTestEngine(LoggerConfig{Output: &buf, SkipPaths: []string{"/healthz"}})
+	serveLoggerRequests(r, "/user/1?verbose=1", "/healthz", "/fail")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 2 requests and 1 error line, got %q", buf.String())
+	}
+	for _, expected := range []string{" 200 ", "10.0.0.1", "GET", `"/user/1?verbose=1"`, "5B", "req-1"} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("%q should contain %q", lines[0], expected)
+		}
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Error("a buffer isn't a terminal, the output shouldn't be colored")
+	}
+	if !strings.Contains(lines[1], " 503 ") || !strings.Contains(lines[2], "db is down") {
+		t.Errorf("unexpected error log %q", lines[1:])
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := newLoggerTestEngine(LoggerConfig{Output: &buf, JSON: true, ForceColor: true})
+	serveLoggerRequests(r, "/user/1")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", buf.String(), err)
+	}
+	if entry["status"] != float64(200) || entry["path"] != "/user/1" || entry["bytes"] != float64(5) ||
+		entry["client_ip"] != "10.0.0.1" || entry["request_id"] != "req-1" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+}
+
+func TestLoggerFormatterAndTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	r := newLoggerTestEngine(LoggerConfig{Output: &buf, ForceColor: true, Formatter: func(p LogFormatterParams) string {
+		return p.StatusCodeColor() + p.Method + p.ResetColor() + "\n"
+	}})
+	serveLoggerRequests(r, "/user/1")
+	if buf.String() != green+"GET"+reset+"\n" {
+		t.Fatalf("unexpected formatter output %q", buf.String())
+	}
+
+	buf.Reset()
+	tmpl := template.Must(template.New("log").Parse("{{.Method}} {{.Path}} {{.StatusCode}}"))
+	r = newLoggerTestEngine(LoggerConfig{Output: &buf, Template: tmpl})
+	serveLoggerRequests(r, "/user/2")
+	if buf.String() != "GET /user/2 200\n" {
+		t.Fatalf("unexpected template output %q", buf.String())
+	}
+}
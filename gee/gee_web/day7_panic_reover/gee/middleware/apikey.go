@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"gee"
+	"net/http"
+)
+
+// ErrAPIKeyInvalid is recorded when the API key is missing or unknown
+var ErrAPIKeyInvalid = errors.New("middleware: invalid API key")
+
+// APIKeyConfig configures APIKey
+type APIKeyConfig struct {
+	// Header carries the key, X-API-Key by default
+	Header string
+	// Query is the query parameter read when the header is missing,
+	// empty to only accept the header (keys in URLs end up in access logs)
+	Query string
+	// Keys maps each accepted key to the name of its client
+	Keys map[string]string
+	// Validator is used instead of Keys, it returns the client name and
+	// whether the key is accepted
+	Validator func(key string) (string, bool)
+}
+
+// APIKey accepts requests carrying a known API key and stores the client
+// name under AuthUserKey. Other requests get 401, with ErrAPIKeyInvalid
+// recorded for ErrorHandler
+func APIKey(conf APIKeyConfig) gee.HandlerFunc {
+	if conf.Header == "" {
+		conf.Header = "X-API-Key"
+	}
+	validate := conf.Validator
+	if validate == nil {
+		if len(conf.Keys) == 0 {
+			panic("middleware: APIKey needs Keys or a Validator")
+		}
+		validate = constantTimeLookup(conf.Keys)
+	}
+
+	return func(ctx *gee.Context) {
+		key := ctx.Req.Header.Get(conf.Header)
+		if key == "" && conf.Query != "" {
+			key = ctx.Query(conf.Query)
+		}
+		client, ok := "", false
+		if key != "" {
+			client, ok = validate(key)
+		}
+		if !ok {
+			ctx.AbortWithError(http.StatusUnauthorized, ErrAPIKeyInvalid).SetType(gee.ErrorTypePublic)
+			return
+		}
+		ctx.Set(AuthUserKey, client)
+		ctx.Next()
+	}
+}
+
+// constantTimeLookup compares key with every known key, so the response
+// time doesn't leak how much of a key was right
+func constantTimeLookup(keys map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		client, ok := "", false
+		for known, name := range keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(known)) == 1 {
+				client, ok = name, true
+			}
+		}
+		return client, ok
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKey(t *testing.T) {
+	r := gee.New()
+	r.Use(gee.ErrorHandler())
+	r.Use(APIKey(APIKeyConfig{Query: "api_key", Keys: map[string]string{"k1": "billing"}}))
+	r.GET("/", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, ctx.GetString(AuthUserKey))
+	})
+
+	cases := []struct {
+		header, url string
+		code        int
+	}{
+		{"k1", "/", http.StatusOK},
+		{"", "/?api_key=k1", http.StatusOK},
+		{"k2", "/", http.StatusUnauthorized},
+		{"", "/", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.url, nil)
+		if c.header != "" {
+			req.Header.Set("X-API-Key", c.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("key %q url %s: expected %d, got %d", c.header, c.url, c.code, w.Code)
+		}
+		if c.code == http.StatusOK && w.Body.String() != "billing" {
+			t.Errorf("expected client billing, got %q", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"gee"
+	"net/http"
+	"strconv"
+)
+
+// AuthUserKey is the Context key holding the authenticated user, set by
+// BasicAuth and APIKey
+const AuthUserKey = "user"
+
+// BasicAuth checks HTTP Basic credentials against accounts, user -> password,
+// see BasicAuthForRealm
+func BasicAuth(accounts map[string]string) gee.HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm checks HTTP Basic credentials against accounts and stores
+// the user under AuthUserKey. Other requests get 401 with a challenge for
+// realm, "Authorization Required" by default
+func BasicAuthForRealm(accounts map[string]string, realm string) gee.HandlerFunc {
+	if len(accounts) == 0 {
+		panic("middleware: BasicAuth needs at least one account")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+
+	// compare whole headers so the check doesn't depend on which user matched
+	type pair struct {
+		user   string
+		header []byte
+	}
+	pairs := make([]pair, 0, len(accounts))
+	for user, password := range accounts {
+		if user == "" {
+			panic("middleware: BasicAuth user can't be empty")
+		}
+		value := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+		pairs = append(pairs, pair{user: user, header: []byte("Basic " + value)})
+	}
+
+	return func(ctx *gee.Context) {
+		header := []byte(ctx.Req.Header.Get("Authorization"))
+		user := ""
+		for _, p := range pairs {
+			if subtle.ConstantTimeCompare(header, p.header) == 1 {
+				user = p.user
+			}
+		}
+		if user == "" {
+			ctx.Writer.Header().Set("WWW-Authenticate", challenge)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		ctx.Set(AuthUserKey, user)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := gee.New()
+	admin := r.Group("/admin")
+	admin.Use(BasicAuth(map[string]string{"geektutu": "secret", "sam": "1234"}))
+	admin.GET("/", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, ctx.GetString(AuthUserKey))
+	})
+
+	req := httptest.NewRequest("GET", "/admin/", nil)
+	req.SetBasicAuth("sam", "1234")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "sam" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	req.SetBasicAuth("sam", "wrong")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Basic realm="Authorization Required"` {
+		t.Fatalf("expected a 401 challenge, got %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"gee"
+	"io"
+	"net/http"
+)
+
+// ErrBodyTooLarge is recorded with Context.Error when BodyLimit rejects a body
+var ErrBodyTooLarge = errors.New("middleware: request body too large")
+
+// limitedBody notices when http.MaxBytesReader cuts the body off
+type limitedBody struct {
+	io.ReadCloser
+	limit, read int64
+	exceeded    bool
+}
+
+func (b *limitedBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		b.exceeded = true
+	}
+	return
+}
+
+// BodyLimit caps request bodies at n bytes with http.MaxBytesReader.
+// A declared Content-Length above n is refused up front; a body growing
+// past n makes reads fail and, if the handler didn't respond, the
+// response becomes 413 Request Entity Too Large
+func BodyLimit(n int64) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		if ctx.Req.ContentLength > n {
+			ctx.Error(ErrBodyTooLarge)
+			ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if ctx.Req.Body == nil || ctx.Req.Body == http.NoBody {
+			ctx.Next()
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(ctx.Writer, ctx.Req.Body, n), limit: n}
+		ctx.Req.Body = body
+		ctx.Next()
+
+		if body.exceeded && !ctx.Writer.Written() {
+			ctx.Error(ErrBodyTooLarge)
+			ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"gee"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	r := gee.New()
+	r.Use(BodyLimit(8))
+	r.POST("/", func(ctx *gee.Context) {
+		body, err := ioutil.ReadAll(ctx.Req.Body)
+		if err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "%s", body)
+	})
+
+	cases := []struct {
+		body          string
+		contentLength int64
+		code          int
+	}{
+		{"12345678", 8, http.StatusOK},
+		{"123456789", 9, http.StatusRequestEntityTooLarge},
+		{"123456789", -1, http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(c.body))
+		req.ContentLength = c.contentLength
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("body %q with Content-Length %d: expected %d, got %d", c.body, c.contentLength, c.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"gee"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures CORS
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins, "*" allows any origin
+	AllowOrigins []string
+	// AllowOriginFunc decides for origins missing from AllowOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods answers preflight requests, common methods by default
+	AllowMethods []string
+	// AllowHeaders answers preflight requests, by default the headers the
+	// browser asked for are allowed
+	AllowHeaders []string
+	// ExposeHeaders lists the response headers readable by scripts
+	ExposeHeaders []string
+	// AllowCredentials lets requests carry cookies and HTTP auth, it can't
+	// be combined with the "*" origin
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
+// CORS answers cross-origin requests. Preflight requests are answered with
+// 204 and stop the chain, so register CORS on the engine to also cover
+// routes without an OPTIONS handler
+func CORS(conf CORSConfig) gee.HandlerFunc {
+	allowAll := false
+	origins := make(map[string]struct{}, len(conf.AllowOrigins))
+	for _, origin := range conf.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[strings.ToLower(origin)] = struct{}{}
+	}
+	if allowAll && conf.AllowCredentials {
+		// echoing any origin with credentials lets every site read the responses
+		panic("middleware: CORS can't allow credentials for any origin, list the origins or use AllowOriginFunc")
+	}
+	if len(conf.AllowMethods) == 0 {
+		conf.AllowMethods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(conf.AllowMethods, ", ")
+	allowHeaders := strings.Join(conf.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(conf.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(conf.MaxAge / time.Second))
+
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		if _, ok := origins[strings.ToLower(origin)]; ok {
+			return true
+		}
+		return conf.AllowOriginFunc != nil && conf.AllowOriginFunc(origin)
+	}
+
+	return func(ctx *gee.Context) {
+		origin := ctx.Req.Header.Get("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+
+		header := ctx.Writer.Header()
+		header.Add("Vary", "Origin")
+		preflight := ctx.Method == http.MethodOptions && ctx.Req.Header.Get("Access-Control-Request-Method") != ""
+		if !allowed(origin) {
+			if preflight {
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// the browser refuses the response without the CORS headers
+			ctx.Next()
+			return
+		}
+
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if conf.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			ctx.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := ctx.Req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if conf.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
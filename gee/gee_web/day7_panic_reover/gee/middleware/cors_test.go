@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCORSEngine(conf CORSConfig) *gee.Engine {
+	r := gee.New()
+	r.Use(CORS(conf))
+	r.GET("/api", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+func TestCORSPreflight(t *testing.T) {
+	r := newCORSEngine(CORSConfig{
+		AllowOrigins:     []string{"https://geektutu.com"},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/api", nil)
+	req.Header.Set("Origin", "https://geektutu.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://geektutu.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS",
+		"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+		"Access-Control-Max-Age":           "3600",
+	}
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", w.Code)
+	}
+	for key, value := range expected {
+		if w.Header().Get(key) != value {
+			t.Errorf("expected %s %q, got %q", key, value, w.Header().Get(key))
+		}
+	}
+
+	req.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("a preflight from an unknown origin should be refused, got %d", w.Code)
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	r := newCORSEngine(CORSConfig{AllowOrigins: []string{"*"}, ExposeHeaders: []string{"X-Request-ID"}})
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("Origin", "https://any.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "*" ||
+		w.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api", nil))
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("same-origin requests don't need CORS headers")
+	}
+}
+
+func TestCORSCredentialsWithAnyOrigin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("allowing credentials for any origin should panic")
+		}
+	}()
+	CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"gee"
+	"gee/sessions"
+	"html/template"
+	"net/http"
+)
+
+// ErrCSRFInvalid is recorded when an unsafe request has no valid CSRF token
+var ErrCSRFInvalid = errors.New("middleware: invalid CSRF token")
+
+// csrfTokenKey is the Context key holding the token of the request
+const csrfTokenKey = "csrfToken"
+
+const csrfSecretLength = 32
+
+// CSRFConfig configures CSRF
+type CSRFConfig struct {
+	// SessionKey stores the secret in the session, "_csrf" by default
+	SessionKey string
+	// Header carries the token of AJAX requests, X-CSRF-Token by default
+	Header string
+	// Field is the form field carrying the token, "_csrf" by default
+	Field string
+	// FuncName is the template function emitting the hidden input,
+	// "csrfField" by default
+	FuncName string
+	// Failed responds to requests without a valid token, by default
+	// 403 Forbidden with a JSON body
+	Failed gee.HandlerFunc
+}
+
+// CSRF protects the unsafe methods (all but GET, HEAD, OPTIONS and TRACE)
+// with a per-session secret, requests must carry a token from CSRFToken in
+// the Header or the form Field. It needs sessions.Sessions earlier in the
+// chain, and registers the FuncName template function on engine, so it
+// must come before LoadHTMLGlob:
+//
+//	r.Use(sessions.Sessions("gee_session", store), middleware.CSRF(r, middleware.CSRFConfig{}))
+//	r.LoadHTMLGlob("templates/*")
+//	...
+//	ctx.HTML(http.StatusOK, "form.tmpl", gee.H{"csrf": middleware.CSRFToken(ctx)})
+//
+// and in form.tmpl: <form method="post">{{ csrfField .csrf }}...</form>
+func CSRF(engine *gee.Engine, conf CSRFConfig) gee.HandlerFunc {
+	if conf.SessionKey == "" {
+		conf.SessionKey = "_csrf"
+	}
+	if conf.Header == "" {
+		conf.Header = "X-CSRF-Token"
+	}
+	if conf.Field == "" {
+		conf.Field = "_csrf"
+	}
+	if conf.FuncName == "" {
+		conf.FuncName = "csrfField"
+	}
+	if conf.Failed == nil {
+		conf.Failed = func(ctx *gee.Context) {
+			ctx.Fail(http.StatusForbidden, "Forbidden")
+		}
+	}
+
+	field := template.HTMLEscapeString(conf.Field)
+	engine.AddFuncMap(conf.FuncName, func(token string) template.HTML {
+		return template.HTML(`<input type="hidden" name="` + field + `" value="` +
+			template.HTMLEscapeString(token) + `">`)
+	})
+
+	return func(ctx *gee.Context) {
+		s := sessions.Default(ctx)
+		secret, _ := s.Get(conf.SessionKey).([]byte)
+		if len(secret) != csrfSecretLength {
+			secret = make([]byte, csrfSecretLength)
+			if _, err := rand.Read(secret); err != nil {
+				ctx.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+			s.Set(conf.SessionKey, secret)
+			if err := s.Save(); err != nil {
+				ctx.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+		}
+		ctx.Set(csrfTokenKey, maskToken(secret))
+
+		switch ctx.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			ctx.Next()
+			return
+		}
+		token := ctx.Req.Header.Get(conf.Header)
+		if token == "" {
+			token = ctx.Req.PostFormValue(conf.Field)
+		}
+		if !validToken(token, secret) {
+			ctx.Error(ErrCSRFInvalid)
+			conf.Failed(ctx)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// CSRFToken returns the token to embed in the forms of the response
+func CSRFToken(ctx *gee.Context) string {
+	return ctx.GetString(csrfTokenKey)
+}
+
+// maskToken XORs secret with a one-time pad, so the token differs on every
+// response and can't be recovered from compressed responses (BREACH)
+func maskToken(secret []byte) string {
+	token := make([]byte, 2*len(secret))
+	pad := token[:len(secret)]
+	if _, err := rand.Read(pad); err != nil {
+		panic("middleware: cannot generate a CSRF token: " + err.Error())
+	}
+	for i := range secret {
+		token[len(secret)+i] = pad[i] ^ secret[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+func validToken(token string, secret []byte) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 2*len(secret) {
+		return false
+	}
+	pad, masked := raw[:len(secret)], raw[len(secret):]
+	unmasked := make([]byte, len(secret))
+	for i := range secret {
+		unmasked[i] = pad[i] ^ masked[i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, secret) == 1
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"gee"
+	"gee/sessions"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSRF(t *testing.T) {
+	dir := t.TempDir()
+	form := `<form method="post">{{ csrfField .csrf }}</form>`
+	if err := ioutil.WriteFile(filepath.Join(dir, "form.tmpl"), []byte(form), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gee.New()
+	r.Use(sessions.Sessions("gee_session", sessions.NewMemoryStore(time.Hour)), CSRF(r, CSRFConfig{}))
+	r.LoadHTMLGlob(filepath.Join(dir, "*"))
+	r.GET("/form", func(ctx *gee.Context) {
+		ctx.HTML(http.StatusOK, "form.tmpl", gee.H{"csrf": CSRFToken(ctx)})
+	})
+	r.POST("/form", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, "saved")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/form", nil))
+	match := regexp.MustCompile(`<input type="hidden" name="_csrf" value="([\w-]+)">`).FindStringSubmatch(w.Body.String())
+	cookies := w.Result().Cookies()
+	if match == nil || len(cookies) != 1 {
+		t.Fatalf("expected a hidden input and a session cookie, got %q %v", w.Body.String(), cookies)
+	}
+	token := match[1]
+
+	post := func(form url.Values, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/form", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		req.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w = post(url.Values{"_csrf": {token}}, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the form token to be accepted, got %d", w.Code)
+	}
+	if w = post(nil, token); w.Code != http.StatusOK {
+		t.Fatalf("expected the header token to be accepted, got %d", w.Code)
+	}
+	if w = post(nil, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without a token, got %d", w.Code)
+	}
+	if w = post(url.Values{"_csrf": {maskToken(make([]byte, csrfSecretLength))}}, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 with the token of another secret, got %d", w.Code)
+	}
+	if maskToken([]byte("secret")) == maskToken([]byte("secret")) {
+		t.Fatal("tokens should be masked differently on every call")
+	}
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"gee"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// GzipConfig configures Gzip
+type GzipConfig struct {
+	// Level is the compression level, gzip.DefaultCompression by default
+	Level int
+	// MinLength is the body size below which responses are sent as is,
+	// compressing tiny bodies costs more than it saves
+	MinLength int
+	// ExcludedPaths are path prefixes never compressed
+	ExcludedPaths []string
+	// ExcludedExtensions are file extensions never compressed, e.g. .png
+	ExcludedExtensions []string
+}
+
+// Gzip compresses responses with gzip, or deflate for clients that only
+// accept deflate. Streams (Server-Sent Events, WebSocket upgrades) are
+// left alone
+func Gzip(conf GzipConfig) gee.HandlerFunc {
+	if conf.Level == 0 {
+		conf.Level = gzip.DefaultCompression
+	}
+	excludedExtensions := make(map[string]struct{}, len(conf.ExcludedExtensions))
+	for _, ext := range conf.ExcludedExtensions {
+		excludedExtensions[strings.ToLower(ext)] = struct{}{}
+	}
+
+	return func(ctx *gee.Context) {
+		encoding := acceptedEncoding(ctx.Req.Header.Get("Accept-Encoding"))
+		if encoding == "" || !compressible(ctx.Req, conf.ExcludedPaths, excludedExtensions) {
+			ctx.Next()
+			return
+		}
+
+		w := &compressWriter{
+			ResponseWriter: ctx.Writer,
+			encoding:       encoding,
+			level:          conf.Level,
+			minLength:      conf.MinLength,
+		}
+		ctx.Writer = w
+		defer func() {
+			w.close()
+			ctx.Writer = w.ResponseWriter
+		}()
+		ctx.Next()
+	}
+}
+
+// acceptedEncoding picks gzip over deflate from an Accept-Encoding header
+func acceptedEncoding(header string) string {
+	gzipOK, deflateOK := false, false
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(params) > 1 && strings.Replace(strings.TrimSpace(params[1]), " ", "", -1) == "q=0" {
+			continue
+		}
+		switch name {
+		case "gzip", "*":
+			gzipOK = true
+		case "deflate":
+			deflateOK = true
+		}
+	}
+	switch {
+	case gzipOK:
+		return "gzip"
+	case deflateOK:
+		return "deflate"
+	}
+	return ""
+}
+
+func compressible(req *http.Request, excludedPaths []string, excludedExtensions map[string]struct{}) bool {
+	if strings.Contains(strings.ToLower(req.Header.Get("Connection")), "upgrade") ||
+		strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		return false
+	}
+	for _, prefix := range excludedPaths {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return false
+		}
+	}
+	_, excluded := excludedExtensions[strings.ToLower(path.Ext(req.URL.Path))]
+	return !excluded
+}
+
+// compressWriter buffers the body until it reaches minLength, then
+// switches to compressing, or writes it as is when the handler finishes first
+type compressWriter struct {
+	gee.ResponseWriter
+	encoding   string
+	level      int
+	minLength  int
+	buf        []byte
+	compressor io.WriteCloser
+	decided    bool
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.compressor != nil {
+			return w.compressor.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written is true as soon as the handler wrote something, even if it is
+// still buffered, so nothing else tries to write a second response
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// decide sends the headers and the buffered body, compressed or not
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if compress && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+
+		var err error
+		if w.encoding == "gzip" {
+			w.compressor, err = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+		} else {
+			w.compressor, err = flate.NewWriter(w.ResponseWriter, w.level)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) > 0)
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// close finishes the body once the chain returns
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"gee"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newGzipEngine() *gee.Engine {
+	r := gee.New()
+	r.Use(Gzip(GzipConfig{MinLength: 64, ExcludedPaths: []string{"/raw"}, ExcludedExtensions: []string{".png"}}))
+	long := strings.Repeat("gee ", 100)
+	r.GET("/long", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, long)
+	})
+	r.GET("/short", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, "short")
+	})
+	r.GET("/raw/long", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, long)
+	})
+	r.GET("/logo.png", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, long)
+	})
+	return r
+}
+
+func getWithEncoding(r *gee.Engine, path, encoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Accept-Encoding", encoding)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGzip(t *testing.T) {
+	r := newGzipEngine()
+	w := getWithEncoding(r, "/long", "gzip, deflate")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected a gzip response, got headers %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(zr)
+	if string(body) != strings.Repeat("gee ", 100) {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	for _, path := range []string{"/short", "/raw/long", "/logo.png"} {
+		w = getWithEncoding(r, path, "gzip")
+		if w.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(w.Body.String(), "gee ") && path != "/short" {
+			t.Errorf("%s shouldn't be compressed", path)
+		}
+	}
+	if w = getWithEncoding(r, "/short", "gzip"); w.Body.String() != "short" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestDeflate(t *testing.T) {
+	w := getWithEncoding(newGzipEngine(), "/long", "deflate, gzip;q=0")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("expected a deflate response, got headers %v", w.Header())
+	}
+	var body strings.Builder
+	io.Copy(&body, flate.NewReader(w.Body))
+	if body.String() != strings.Repeat("gee ", 100) {
+		t.Fatalf("unexpected body %q", body.String())
+	}
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gee"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTClaimsKey is the Context key holding the Claims verified by JWT
+const JWTClaimsKey = "jwtClaims"
+
+var (
+	ErrTokenMissing     = errors.New("middleware: bearer token missing")
+	ErrTokenMalformed   = errors.New("middleware: token malformed")
+	ErrTokenAlgorithm   = errors.New("middleware: token algorithm not supported")
+	ErrTokenSignature   = errors.New("middleware: token signature invalid")
+	ErrTokenExpired     = errors.New("middleware: token expired")
+	ErrTokenNotValidYet = errors.New("middleware: token not valid yet")
+	ErrTokenIssuer      = errors.New("middleware: token issuer invalid")
+)
+
+// Claims is the payload of a JWT
+type Claims map[string]interface{}
+
+// Subject returns the sub claim
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Issuer returns the iss claim
+func (c Claims) Issuer() string {
+	iss, _ := c["iss"].(string)
+	return iss
+}
+
+// Time returns a NumericDate claim such as exp, ok is false when it is
+// missing or not a number
+func (c Claims) Time(name string) (t time.Time, ok bool) {
+	var seconds float64
+	switch v := c[name].(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = f
+	case float64:
+		seconds = v
+	case int64:
+		seconds = float64(v)
+	case int:
+		seconds = float64(v)
+	default:
+		return time.Time{}, false
+	}
+	// split before scaling, nanoseconds since 1970 overflow int64 after 2262
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+}
+
+// JWTConfig configures JWT
+type JWTConfig struct {
+	// Secret is the HS256 key
+	Secret []byte
+	// Issuer, when set, must match the iss claim
+	Issuer string
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+	// Now returns the current time, time.Now by default
+	Now func() time.Time
+}
+
+// JWT verifies "Authorization: Bearer <token>" as an HS256 JWT, checks its
+// exp, nbf and iss claims, and stores the Claims under JWTClaimsKey. Other
+// requests get 401 with the reason recorded for ErrorHandler
+func JWT(conf JWTConfig) gee.HandlerFunc {
+	if len(conf.Secret) == 0 {
+		panic("middleware: JWT needs a secret")
+	}
+
+	return func(ctx *gee.Context) {
+		token := bearerToken(ctx.Req.Header.Get("Authorization"))
+		var claims Claims
+		err := ErrTokenMissing
+		if token != "" {
+			claims, err = ParseJWT(token, conf)
+		}
+		if err != nil {
+			ctx.Writer.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			ctx.AbortWithError(http.StatusUnauthorized, err).SetType(gee.ErrorTypePublic)
+			return
+		}
+		ctx.Set(JWTClaimsKey, claims)
+		ctx.Next()
+	}
+}
+
+// GetClaims returns the Claims stored by JWT, nil if there are none
+func GetClaims(ctx *gee.Context) Claims {
+	claims, _ := ctx.Get(JWTClaimsKey)
+	c, _ := claims.(Claims)
+	return c
+}
+
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+var b64 = base64.RawURLEncoding
+
+// SignJWT returns claims as an HS256 JWT signed with secret
+func SignJWT(claims Claims, secret []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := b64.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + b64.EncodeToString(payload)
+	return unsigned + "." + b64.EncodeToString(sign(unsigned, secret)), nil
+}
+
+// ParseJWT verifies token as configured by conf and returns its claims
+func ParseJWT(token string, conf JWTConfig) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	// only HS256, never "none" or an algorithm chosen by the client
+	if header.Alg != "HS256" {
+		return nil, ErrTokenAlgorithm
+	}
+
+	signature, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !hmac.Equal(signature, sign(parts[0]+"."+parts[1], conf.Secret)) {
+		return nil, ErrTokenSignature
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil || claims == nil {
+		return nil, ErrTokenMalformed
+	}
+
+	now := time.Now()
+	if conf.Now != nil {
+		now = conf.Now()
+	}
+	if _, present := claims["exp"]; present {
+		exp, ok := claims.Time("exp")
+		if !ok {
+			return nil, ErrTokenMalformed
+		}
+		if !now.Before(exp.Add(conf.Leeway)) {
+			return nil, ErrTokenExpired
+		}
+	}
+	if _, present := claims["nbf"]; present {
+		nbf, ok := claims.Time("nbf")
+		if !ok {
+			return nil, ErrTokenMalformed
+		}
+		if now.Add(conf.Leeway).Before(nbf) {
+			return nil, ErrTokenNotValidYet
+		}
+	}
+	if conf.Issuer != "" && claims.Issuer() != conf.Issuer {
+		return nil, ErrTokenIssuer
+	}
+	return claims, nil
+}
+
+func sign(unsigned string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+// decodeSegment decodes a base64url JSON segment, numbers are kept as
+// json.Number so large exp values don't lose precision
+func decodeSegment(segment string, v interface{}) error {
+	data, err := b64.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJWT(t *testing.T) {
+	secret := []byte("gee-secret")
+	now := time.Unix(1600000000, 0)
+	conf := JWTConfig{Secret: secret, Issuer: "gee", Now: func() time.Time { return now }}
+	mustSign := func(claims Claims) string {
+		token, err := SignJWT(claims, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	valid := mustSign(Claims{"sub": "geektutu", "iss": "gee", "exp": now.Unix() + 60, "nbf": now.Unix() - 60})
+	claims, err := ParseJWT(valid, conf)
+	if err != nil || claims.Subject() != "geektutu" {
+		t.Fatalf("expected a valid token, got %v %v", claims, err)
+	}
+	if exp, ok := claims.Time("exp"); !ok || !exp.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected exp %v", exp)
+	}
+
+	farFuture := mustSign(Claims{"iss": "gee", "exp": 1e10, "nbf": 1.5})
+	claims, err = ParseJWT(farFuture, conf)
+	if err != nil {
+		t.Fatalf("a far-future exp should be valid, got %v", err)
+	}
+	if exp, _ := claims.Time("exp"); exp.Unix() != 1e10 {
+		t.Fatalf("unexpected far-future exp %v", exp)
+	}
+	if nbf, _ := claims.Time("nbf"); !nbf.Equal(time.Unix(1, 5e8)) {
+		t.Fatalf("unexpected fractional nbf %v", nbf)
+	}
+	if _, err := ParseJWT(mustSign(Claims{"iss": "gee", "nbf": 1e10}), conf); err != ErrTokenNotValidYet {
+		t.Fatalf("a far-future nbf should not be valid yet, got %v", err)
+	}
+
+	parts := strings.Split(valid, ".")
+	cases := map[string]error{
+		"a.b": ErrTokenMalformed,
+		mustSign(Claims{"iss": "gee", "exp": now.Unix()}):                   ErrTokenExpired,
+		mustSign(Claims{"iss": "gee", "nbf": now.Unix() + 60}):              ErrTokenNotValidYet,
+		mustSign(Claims{"iss": "other"}):                                    ErrTokenIssuer,
+		mustSign(Claims{"iss": "gee", "exp": "tomorrow"}):                   ErrTokenMalformed,
+		parts[0] + "." + parts[1] + ".c2lnbmF0dXJl":                         ErrTokenSignature,
+		b64.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".": ErrTokenAlgorithm,
+	}
+	for token, expected := range cases {
+		if _, err := ParseJWT(token, conf); err != expected {
+			t.Errorf("token %s: expected %v, got %v", token, expected, err)
+		}
+	}
+
+	conf.Leeway = 2 * time.Minute
+	if _, err := ParseJWT(mustSign(Claims{"iss": "gee", "exp": now.Unix() - 60}), conf); err != nil {
+		t.Errorf("leeway should accept a recently expired token, got %v", err)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("gee-secret")
+	r := gee.New()
+	r.Use(gee.ErrorHandler())
+	r.Use(JWT(JWTConfig{Secret: secret}))
+	r.GET("/me", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, GetClaims(ctx).Subject())
+	})
+
+	token, _ := SignJWT(Claims{"sub": "geektutu", "exp": time.Now().Add(time.Hour).Unix()}, secret)
+	req := httptest.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/me", nil))
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), ErrTokenMissing.Error()) ||
+		w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 401 for a missing token, got %d %q", w.Code, w.Body.String())
+	}
+}
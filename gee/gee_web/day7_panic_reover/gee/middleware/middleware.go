@@ -0,0 +1,3 @@
+// Package middleware provides the common gee middlewares, each one is a
+// gee.HandlerFunc meant for Engine.Use, RouterGroup.Use or a single route.
+package middleware
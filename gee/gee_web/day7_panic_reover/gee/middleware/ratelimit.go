@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"gee"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitResult is the outcome of taking a token from a bucket
+type RateLimitResult struct {
+	// Allowed is false when the bucket was empty
+	Allowed bool
+	// Limit is the bucket size
+	Limit int
+	// Remaining is the number of tokens left
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, zero when Allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets, one per key. MemoryStore keeps them in
+// the process, implement Store to share them between instances
+type Store interface {
+	// Take removes a token from the bucket of key
+	Take(key string) (RateLimitResult, error)
+}
+
+// KeyFunc returns the bucket key of a request, "" skips rate limiting
+type KeyFunc func(ctx *gee.Context) string
+
+// KeyByClientIP gives every client its own bucket
+func KeyByClientIP(ctx *gee.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// KeyByAPIKey gives every X-API-Key its own bucket, requests without a key
+// are limited by client IP
+func KeyByAPIKey(ctx *gee.Context) string {
+	if key := ctx.Req.Header.Get("X-API-Key"); key != "" {
+		return "key:" + key
+	}
+	return KeyByClientIP(ctx)
+}
+
+// KeyByRoute shares one bucket between all the clients of a route,
+// unmatched requests aren't limited
+func KeyByRoute(ctx *gee.Context) string {
+	if ctx.FullPath() == "" {
+		return ""
+	}
+	return "route:" + ctx.Method + " " + ctx.FullPath()
+}
+
+// RateLimitConfig configures RateLimit
+type RateLimitConfig struct {
+	// Store keeps the buckets, required
+	Store Store
+	// Key picks the bucket of a request, KeyByClientIP by default
+	Key KeyFunc
+	// Limited responds to requests over the limit, by default
+	// 429 Too Many Requests with a JSON body
+	Limited gee.HandlerFunc
+}
+
+// RateLimit throttles requests with token buckets and sets the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+// plus Retry-After on limited requests. When the Store fails the request
+// goes through and the error is recorded with Context.Error
+func RateLimit(conf RateLimitConfig) gee.HandlerFunc {
+	if conf.Store == nil {
+		panic("middleware: RateLimit needs a Store")
+	}
+	if conf.Key == nil {
+		conf.Key = KeyByClientIP
+	}
+	if conf.Limited == nil {
+		conf.Limited = func(ctx *gee.Context) {
+			ctx.Fail(http.StatusTooManyRequests, "Too Many Requests")
+		}
+	}
+
+	return func(ctx *gee.Context) {
+		key := conf.Key(ctx)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		result, err := conf.Store.Take(key)
+		if err != nil {
+			ctx.Error(err)
+			ctx.Next()
+			return
+		}
+
+		header := ctx.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			conf.Limited(ctx)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore is an in-process Store. Each bucket holds limit tokens and
+// refills at limit per period. Buckets that have been full for a while
+// are dropped lazily, so idle clients don't pile up
+type MemoryStore struct {
+	limit     int
+	perSecond float64
+	// idle is how long a full bucket is kept
+	idle time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore allows bursts of limit requests and limit requests per period
+func NewMemoryStore(limit int, period time.Duration) *MemoryStore {
+	if limit <= 0 || period <= 0 {
+		panic("middleware: rate limit and period must be positive")
+	}
+	return &MemoryStore{
+		limit:     limit,
+		perSecond: float64(limit) / period.Seconds(),
+		idle:      period,
+		buckets:   make(map[string]*bucket),
+		now:       time.Now,
+	}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(key string) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(s.limit), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = s.refill(b, now)
+	b.last = now
+
+	result := RateLimitResult{Limit: s.limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = s.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = s.duration(float64(s.limit) - b.tokens)
+	return result, nil
+}
+
+// Len returns the number of buckets held
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+func (s *MemoryStore) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*s.perSecond
+	return math.Min(tokens, float64(s.limit))
+}
+
+func (s *MemoryStore) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / s.perSecond * float64(time.Second))
+}
+
+// sweep drops the buckets idle long enough to be full again, at most once
+// per idle period so Take stays cheap
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.idle {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if s.refill(b, now) >= float64(s.limit) && now.Sub(b.last) >= s.idle {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	s := NewMemoryStore(2, 2*time.Second)
+	s.now = func() time.Time { return now }
+
+	for i, remaining := range []int{1, 0} {
+		result, _ := s.Take("a")
+		if !result.Allowed || result.Remaining != remaining {
+			t.Fatalf("take %d: unexpected result %+v", i, result)
+		}
+	}
+	result, _ := s.Take("a")
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 2*time.Second {
+		t.Fatalf("expected an empty bucket, got %+v", result)
+	}
+	if result, _ = s.Take("b"); !result.Allowed {
+		t.Fatal("buckets should be per key")
+	}
+
+	now = now.Add(time.Second)
+	if result, _ = s.Take("a"); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected one refilled token, got %+v", result)
+	}
+
+	now = now.Add(time.Minute)
+	s.Take("c")
+	if s.Len() != 1 {
+		t.Fatalf("idle buckets should be evicted, %d left", s.Len())
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store down")
+}
+
+func TestRateLimit(t *testing.T) {
+	r := gee.New()
+	r.GET("/ip", RateLimit(RateLimitConfig{Store: NewMemoryStore(1, time.Minute)}), func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	r.GET("/route/:id", RateLimit(RateLimitConfig{Store: NewMemoryStore(1, time.Minute), Key: KeyByRoute}), func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	r.GET("/down", RateLimit(RateLimitConfig{Store: failingStore{}}), func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, "%d", len(ctx.Errors))
+	})
+
+	request := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/ip", "10.0.0.1:1234")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" ||
+		w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("X-RateLimit-Reset") != "60" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	w = request("/ip", "10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+	if w = request("/ip", "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Fatalf("another client should have its own bucket, got %d", w.Code)
+	}
+
+	request("/route/1", "10.0.0.1:1234")
+	if w = request("/route/2", "10.0.0.2:1234"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("clients of a route should share its bucket, got %d", w.Code)
+	}
+
+	if w = request("/down", "10.0.0.1:1234"); w.Code != http.StatusOK || w.Body.String() != "1" {
+		t.Fatalf("a failing store should let requests through, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"gee"
+)
+
+// RequestIDKey is the Context key holding the request ID
+const RequestIDKey = "requestID"
+
+// RequestIDConfig configures RequestIDWithConfig
+type RequestIDConfig struct {
+	// Header carries the ID, gee.HeaderRequestID (X-Request-ID) by default
+	Header string
+	// Generator creates IDs for requests without one, 16 random hex bytes
+	// by default
+	Generator func() string
+}
+
+// RequestID propagates the X-Request-ID header, see RequestIDWithConfig
+func RequestID() gee.HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+// RequestIDWithConfig keeps the ID sent by the client or an upstream proxy,
+// or generates one, and stores it in the Context, the request headers
+// (for outgoing calls) and the response headers
+func RequestIDWithConfig(conf RequestIDConfig) gee.HandlerFunc {
+	if conf.Header == "" {
+		conf.Header = gee.HeaderRequestID
+	}
+	if conf.Generator == nil {
+		conf.Generator = newRequestID
+	}
+
+	return func(ctx *gee.Context) {
+		id := ctx.Req.Header.Get(conf.Header)
+		if !validRequestID(id) {
+			id = conf.Generator()
+			ctx.Req.Header.Set(conf.Header, id)
+		}
+		ctx.Set(RequestIDKey, id)
+		ctx.Writer.Header().Set(conf.Header, id)
+		ctx.Next()
+	}
+}
+
+// GetRequestID returns the ID stored by RequestID
+func GetRequestID(ctx *gee.Context) string {
+	return ctx.GetString(RequestIDKey)
+}
+
+// validRequestID rejects IDs that would garble logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("middleware: cannot generate a request ID: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
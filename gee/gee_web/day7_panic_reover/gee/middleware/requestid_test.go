@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	r := gee.New()
+	r.Use(RequestIDWithConfig(RequestIDConfig{Generator: func() string { return "generated" }}))
+	r.GET("/", func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, GetRequestID(ctx))
+	})
+
+	cases := map[string]string{
+		"":             "generated",
+		"upstream-id":  "upstream-id",
+		"bad id\nline": "generated",
+	}
+	for incoming, expected := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		if incoming != "" {
+			req.Header.Set(gee.HeaderRequestID, incoming)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != expected || w.Header().Get(gee.HeaderRequestID) != expected {
+			t.Errorf("incoming %q: expected %q, got body %q header %q", incoming, expected,
+				w.Body.String(), w.Header().Get(gee.HeaderRequestID))
+		}
+	}
+
+	if id := newRequestID(); len(id) != 32 || id == newRequestID() {
+		t.Fatalf("expected random 32 hex chars, got %q", id)
+	}
+}
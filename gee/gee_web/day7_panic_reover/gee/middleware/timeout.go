@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"context"
+	"gee"
+	"net/http"
+	"time"
+)
+
+// Timeout cancels the request context after d. Cancellation is
+// cooperative: handlers must watch ctx.Done() (or pass ctx to the database
+// and HTTP clients). When the deadline passes before anything is written
+// the response becomes 503 Service Unavailable
+func Timeout(d time.Duration) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		c, cancel := context.WithTimeout(ctx.Req.Context(), d)
+		defer cancel()
+		ctx.Req = ctx.Req.WithContext(c)
+
+		ctx.Next()
+
+		if c.Err() == context.DeadlineExceeded && !ctx.Writer.Written() {
+			ctx.Error(c.Err())
+			ctx.AbortWithStatus(http.StatusServiceUnavailable)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	r := gee.New()
+	r.GET("/slow", Timeout(20*time.Millisecond), func(ctx *gee.Context) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			ctx.String(http.StatusOK, "too late")
+		}
+	})
+	r.GET("/fast", Timeout(time.Second), func(ctx *gee.Context) {
+		ctx.String(http.StatusOK, "fast")
+	})
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expected a quick 503, got %d after %v", w.Code, time.Since(start))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "fast" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
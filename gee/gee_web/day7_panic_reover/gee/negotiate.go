@@ -0,0 +1,154 @@
+package gee
+
+import (
+	"gee/render"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// MIME types understood by binding and content negotiation
+const (
+	MIMEJSON              = "application/json"
+	MIMEHTML              = "text/html"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPlain             = "text/plain"
+	MIMEYAML              = "application/x-yaml"
+	MIMEProtoBuf          = "application/x-protobuf"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// Negotiate holds the candidate bodies of Context.Negotiate,
+// Data is used for every format without a specific value
+type Negotiate struct {
+	Offered  []string // offered MIME types, in order of preference
+	HTMLName string
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	// TextData is written as is when a string or []byte, with %v otherwise
+	TextData interface{}
+	// ProtoBufData must be a proto.Message
+	ProtoBufData interface{}
+	Data         interface{}
+}
+
+// Negotiate renders the offered format that best matches the Accept header,
+// 406 Not Acceptable when none does. Offering a type it can't render is a
+// server error
+func (c *Context) Negotiate(code int, config Negotiate) {
+	pick := func(data interface{}) interface{} {
+		if data != nil {
+			return data
+		}
+		return config.Data
+	}
+
+	switch format := c.NegotiateFormat(config.Offered...); format {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSONData))
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, pick(config.HTMLData))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(config.XMLData))
+	case MIMEYAML:
+		c.YAML(code, pick(config.YAMLData))
+	case MIMEPlain:
+		switch data := pick(config.TextData).(type) {
+		case []byte:
+			c.Render(code, render.Data{MIME: MIMEPlain + "; charset=utf-8", Data: data})
+		case string:
+			c.String(code, "%s", data)
+		default:
+			c.String(code, "%v", data)
+		}
+	case MIMEProtoBuf:
+		msg, ok := pick(config.ProtoBufData).(proto.Message)
+		if !ok {
+			c.Fail(http.StatusInternalServerError, "gee: Negotiate needs a proto.Message for "+MIMEProtoBuf)
+			return
+		}
+		c.ProtoBuf(code, msg)
+	case "":
+		c.Fail(http.StatusNotAcceptable, "the accepted formats are not offered by the server")
+	default:
+		c.Fail(http.StatusInternalServerError, "gee: Negotiate can't render "+format)
+	}
+}
+
+// NegotiateFormat returns the offered MIME type preferred by the Accept
+// header, the first offer when there is no Accept header and "" when
+// nothing matches
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	accept := c.Req.Header.Get("Accept")
+	if accept == "" {
+		return offered[0]
+	}
+
+	for _, accepted := range parseAccept(accept) {
+		for _, offer := range offered {
+			if mimeMatch(accepted, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// parseAccept returns the media ranges of an Accept header, by descending q
+func parseAccept(header string) []string {
+	type mediaRange struct {
+		value string
+		q     float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.TrimSpace(params[0])
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{value: value, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	accepted := make([]string, len(ranges))
+	for i, r := range ranges {
+		accepted[i] = r.value
+	}
+	return accepted
+}
+
+// mimeMatch matches offer against an accepted media range such as */* or text/*
+func mimeMatch(accepted, offer string) bool {
+	if accepted == "*/*" || accepted == offer {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(offer, accepted[:len(accepted)-1])
+	}
+	return false
+}
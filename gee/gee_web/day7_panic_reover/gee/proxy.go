@@ -0,0 +1,191 @@
+package gee
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SetTrustedProxies sets the proxies, as IPs or CIDRs, whose forwarding
+// headers ClientIP, Scheme and Host believe. None are trusted by default,
+// so a client can't spoof its address with X-Forwarded-For
+//
+//	r.SetTrustedProxies([]string{"10.0.0.0/8", "127.0.0.1"})
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("gee: invalid trusted proxy %q", proxy)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("gee: invalid trusted proxy %q: %v", proxy, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	engine.trustedCIDRs = cidrs
+	return nil
+}
+
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	if engine == nil || ip == nil {
+		return false
+	}
+	for _, cidr := range engine.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the peer address of the connection
+func (c *Context) remoteIP() net.IP {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// fromTrustedProxy reports whether the peer is a trusted proxy
+func (c *Context) fromTrustedProxy() bool {
+	return c.engine.isTrustedProxy(c.remoteIP())
+}
+
+// ClientIP returns the IP address of the client. When the peer is a trusted
+// proxy (see SetTrustedProxies), the X-Forwarded-For, Forwarded or X-Real-IP
+// header is walked from the nearest hop back, through trusted proxies only,
+// and the first untrusted address is the client
+func (c *Context) ClientIP() string {
+	remote := c.remoteIP()
+	if remote == nil {
+		return ""
+	}
+	if !c.engine.isTrustedProxy(remote) {
+		return remote.String()
+	}
+
+	header := c.Req.Header
+	var hops []string
+	switch {
+	case len(header.Values("X-Forwarded-For")) > 0:
+		for _, value := range header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	case len(header.Values("Forwarded")) > 0:
+		for _, element := range forwardedElements(header) {
+			hops = append(hops, element["for"])
+		}
+	case header.Get("X-Real-IP") != "":
+		hops = []string{header.Get("X-Real-IP")}
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHopIP(hops[i])
+		if ip == nil {
+			// garbage or an obfuscated hop, the last known hop is all we have
+			break
+		}
+		client = ip
+		if !c.engine.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// Scheme returns http or https, as seen by the client. Behind a trusted
+// proxy it honors the X-Forwarded-Proto or Forwarded proto= set by that
+// proxy, the last entry, as earlier ones may come from the client
+func (c *Context) Scheme() string {
+	if c.fromTrustedProxy() {
+		proto := lastValue(c.Req.Header, "X-Forwarded-Proto")
+		if proto == "" {
+			if elements := forwardedElements(c.Req.Header); len(elements) > 0 {
+				proto = elements[len(elements)-1]["proto"]
+			}
+		}
+		if proto = strings.ToLower(proto); proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+	if c.Req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host requested by the client. Behind a trusted proxy
+// it honors the last X-Forwarded-Host or Forwarded host=, like Scheme
+func (c *Context) Host() string {
+	if c.fromTrustedProxy() {
+		if host := lastValue(c.Req.Header, "X-Forwarded-Host"); host != "" {
+			return host
+		}
+		if elements := forwardedElements(c.Req.Header); len(elements) > 0 {
+			if host := elements[len(elements)-1]["host"]; host != "" {
+				return host
+			}
+		}
+	}
+	return c.Req.Host
+}
+
+// lastValue returns the last entry of a comma separated header, the one
+// added by the proxy closest to the server
+func lastValue(header http.Header, key string) string {
+	values := header.Values(key)
+	if len(values) == 0 {
+		return ""
+	}
+	value := values[len(values)-1]
+	if i := strings.LastIndexByte(value, ','); i >= 0 {
+		value = value[i+1:]
+	}
+	return strings.TrimSpace(value)
+}
+
+// forwardedElements parses the Forwarded header of RFC 7239, one map of
+// lower-cased parameters per hop, the client side first
+func forwardedElements(header http.Header) []map[string]string {
+	var elements []map[string]string
+	for _, value := range header.Values("Forwarded") {
+		for _, element := range strings.Split(value, ",") {
+			params := make(map[string]string)
+			for _, pair := range strings.Split(element, ";") {
+				i := strings.IndexByte(pair, '=')
+				if i < 0 {
+					continue
+				}
+				key := strings.ToLower(strings.TrimSpace(pair[:i]))
+				params[key] = strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+			}
+			elements = append(elements, params)
+		}
+	}
+	return elements
+}
+
+// parseHopIP parses a hop of a forwarding header: an IP, optionally with
+// a port, IPv6 possibly in brackets
+func parseHopIP(hop string) net.IP {
+	hop = strings.TrimSpace(hop)
+	if ip := net.ParseIP(hop); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.Trim(hop, "[]"))
+}
@@ -0,0 +1,96 @@
+package gee
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newProxyContext(t *testing.T, trusted []string, remoteAddr string, header map[string]string) *Context {
+	engine := New()
+	if err := engine.SetTrustedProxies(trusted); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "http://gee.local/", nil)
+	req.RemoteAddr = remoteAddr
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return engine.acquireContext(httptest.NewRecorder(), req)
+}
+
+func TestClientIP(t *testing.T) {
+	proxies := []string{"10.0.0.0/8", "2001:db8::1"}
+	cases := []struct {
+		name     string
+		trusted  []string
+		remote   string
+		header   map[string]string
+		expected string
+	}{
+		{"no trusted proxy", nil, "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, "10.0.0.1"},
+		{"untrusted peer", proxies, "8.8.8.8:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, "8.8.8.8"},
+		{"one hop", proxies, "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, "1.1.1.1"},
+		{"spoofed hop", proxies, "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 1.1.1.1, 10.0.0.2"}, "1.1.1.1"},
+		{"all trusted", proxies, "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"garbage hop", proxies, "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, nonsense"}, "10.0.0.1"},
+		{"ipv6 proxy", proxies, "[2001:db8::1]:1234", map[string]string{"X-Forwarded-For": "2001:db8::2"}, "2001:db8::2"},
+		{"x-real-ip", proxies, "10.0.0.1:1234", map[string]string{"X-Real-IP": "1.1.1.1"}, "1.1.1.1"},
+		{"forwarded", proxies, "10.0.0.1:1234", map[string]string{"Forwarded": `for=1.1.1.1;proto=https, for="[2001:db8::1]:4711"`}, "1.1.1.1"},
+		{"obfuscated", proxies, "10.0.0.1:1234", map[string]string{"Forwarded": "for=_hidden"}, "10.0.0.1"},
+		{"bad remote addr", proxies, "pipe", nil, ""},
+	}
+	for _, c := range cases {
+		if ip := newProxyContext(t, c.trusted, c.remote, c.header).ClientIP(); ip != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, ip)
+		}
+	}
+}
+
+func TestSchemeAndHost(t *testing.T) {
+	header := map[string]string{"X-Forwarded-Proto": "http, https", "X-Forwarded-Host": "evil.com, geektutu.com"}
+
+	c := newProxyContext(t, []string{"10.0.0.1"}, "10.0.0.1:1234", header)
+	if c.Scheme() != "https" || c.Host() != "geektutu.com" {
+		t.Fatalf("expected the forwarded scheme and host, got %s %s", c.Scheme(), c.Host())
+	}
+
+	c = newProxyContext(t, nil, "10.0.0.1:1234", header)
+	if c.Scheme() != "http" || c.Host() != "gee.local" {
+		t.Fatalf("an untrusted peer can't set the scheme or host, got %s %s", c.Scheme(), c.Host())
+	}
+
+	c = newProxyContext(t, []string{"10.0.0.1"}, "10.0.0.1:1234", map[string]string{"Forwarded": "proto=http;host=evil.com, proto=https;host=gee.io"})
+	if c.Scheme() != "https" || c.Host() != "gee.io" {
+		t.Fatalf("expected the Forwarded scheme and host, got %s %s", c.Scheme(), c.Host())
+	}
+
+	c = newProxyContext(t, nil, "1.1.1.1:1234", nil)
+	c.Req.TLS = &tls.ConnectionState{}
+	if c.Scheme() != "https" {
+		t.Fatalf("expected https over TLS, got %s", c.Scheme())
+	}
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	r := New()
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local"} {
+		if err := r.SetTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+
+	r.SetTrustedProxies([]string{"127.0.0.1"})
+	r.GET("/", func(ctx *Context) {
+		ctx.String(http.StatusOK, ctx.ClientIP())
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "1.1.1.1" {
+		t.Fatalf("expected the forwarded client, got %q", w.Body.String())
+	}
+}
@@ -1,26 +1,143 @@
 package gee
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"os"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// RecoveryFunc handles a recovered panic, err is the value passed to panic
+type RecoveryFunc func(ctx *Context, err interface{})
+
+// RecoveryConfig configures RecoveryWithConfig
+type RecoveryConfig struct {
+	// Output receives the panic reports, nil disables them
+	Output io.Writer
+	// Handle responds to the panic, by default a 500 JSON body unless a
+	// response has already been written. It isn't called for broken pipes
+	Handle RecoveryFunc
+	// DumpRequest adds the request headers to the report, with credentials
+	// such as Authorization and Cookie redacted
+	DumpRequest bool
+}
+
+// sensitiveHeaders are redacted from request dumps
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// Recovery recovers from panics, reports them with a stack trace on
+// os.Stderr and responds 500
 func Recovery() HandlerFunc {
+	return RecoveryWithConfig(RecoveryConfig{Output: os.Stderr})
+}
+
+// CustomRecovery is Recovery with handle responding to the panic
+func CustomRecovery(handle RecoveryFunc) HandlerFunc {
+	return RecoveryWithConfig(RecoveryConfig{Output: os.Stderr, Handle: handle})
+}
+
+// RecoveryWithWriter is Recovery reporting panics to out, e.g. a crash reporter
+func RecoveryWithWriter(out io.Writer, handle ...RecoveryFunc) HandlerFunc {
+	conf := RecoveryConfig{Output: out}
+	if len(handle) > 0 {
+		conf.Handle = handle[0]
+	}
+	return RecoveryWithConfig(conf)
+}
+
+// RecoveryWithConfig returns a Recovery middleware configured by conf.
+// A panic caused by the client going away (broken pipe, connection reset)
+// is only recorded with Context.Error, as nobody is left to read a response
+func RecoveryWithConfig(conf RecoveryConfig) HandlerFunc {
+	handle := conf.Handle
+	if handle == nil {
+		handle = defaultHandleRecovery
+	}
+
 	return func(ctx *Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				ctx.Fail(http.StatusInternalServerError, "Interval Server Error")
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			brokenPipe := isBrokenPipe(err)
+			if conf.Output != nil {
+				var report strings.Builder
+				fmt.Fprintf(&report, "[Recovery] %s ", time.Now().Format("2006/01/02 - 15:04:05"))
+				if brokenPipe {
+					fmt.Fprintf(&report, "broken connection: %v\n", err)
+				} else {
+					fmt.Fprintf(&report, "panic recovered:\n%s\n", trace(fmt.Sprintf("%v", err)))
+				}
+				if conf.DumpRequest {
+					report.WriteString(dumpRequest(ctx.Req))
+				}
+				report.WriteString("\n")
+				io.WriteString(conf.Output, report.String())
 			}
+
+			if brokenPipe {
+				ctx.Error(err.(error))
+				ctx.Abort()
+				return
+			}
+			handle(ctx, err)
 		}()
 		ctx.Next()
 	}
 }
 
+func defaultHandleRecovery(ctx *Context, _ interface{}) {
+	if ctx.Writer.Written() {
+		// the status line is already out, only stop the chain
+		ctx.Abort()
+		return
+	}
+	ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
+}
+
+// isBrokenPipe reports whether err comes from writing to a connection the
+// client has closed
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(e, &opErr) {
+		msg := strings.ToLower(opErr.Err.Error())
+		return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+	}
+	return false
+}
+
+// dumpRequest returns the request line and headers with credentials redacted
+func dumpRequest(req *http.Request) string {
+	redacted := *req
+	redacted.Header = req.Header.Clone()
+	for _, key := range sensitiveHeaders {
+		if redacted.Header.Get(key) != "" {
+			redacted.Header.Set(key, "*")
+		}
+	}
+	dump, err := httputil.DumpRequest(&redacted, false)
+	if err != nil {
+		return fmt.Sprintf("request dump failed: %v\n", err)
+	}
+	return strings.Replace(string(dump), "\r\n", "\n", -1)
+}
+
 // print stack trace for debug
 func trace(message string) string {
 	var pcs [32]uintptr
@@ -0,0 +1,80 @@
+package gee
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecoveryResponds500(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(RecoveryWithConfig(RecoveryConfig{Output: &buf, DumpRequest: true}))
+	r.GET("/panic", func(ctx *Context) {
+		names := []string{"geektutu"}
+		ctx.String(http.StatusOK, names[100])
+	})
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Trace", "visible")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	report := buf.String()
+	for _, expected := range []string{"panic recovered", "index out of range", "Traceback:", "X-Trace: visible", "Authorization: *"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("report should contain %q:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "secret") {
+		t.Errorf("credentials should be redacted:\n%s", report)
+	}
+}
+
+func TestCustomRecovery(t *testing.T) {
+	r := New()
+	r.Use(RecoveryWithWriter(nil, func(ctx *Context, err interface{}) {
+		ctx.AbortWithStatusJSON(http.StatusBadGateway, H{"panic": err})
+	}))
+	r.GET("/panic", func(ctx *Context) {
+		panic("upstream gone")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusBadGateway || w.Body.String() != `{"panic":"upstream gone"}` {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.EPIPE, syscall.ECONNRESET} {
+		var buf bytes.Buffer
+		handled := false
+		r := New()
+		r.Use(RecoveryWithWriter(&buf, func(ctx *Context, err interface{}) {
+			handled = true
+		}))
+		r.GET("/", func(ctx *Context) {
+			panic(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", errno)})
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if handled || w.Body.Len() != 0 {
+			t.Errorf("%v: a broken connection shouldn't be answered", errno)
+		}
+		if !strings.Contains(buf.String(), "broken connection") {
+			t.Errorf("%v: unexpected report %q", errno, buf.String())
+		}
+	}
+}
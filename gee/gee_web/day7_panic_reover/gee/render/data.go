@@ -0,0 +1,18 @@
+package render
+
+import "io"
+
+// Data writes raw bytes, with an optional MIME type
+type Data struct {
+	MIME string
+	Data []byte
+}
+
+func (r Data) Render(w io.Writer) error {
+	_, err := w.Write(r.Data)
+	return err
+}
+
+func (r Data) ContentType() string {
+	return r.MIME
+}
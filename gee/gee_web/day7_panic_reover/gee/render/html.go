@@ -0,0 +1,62 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// HTMLRender creates the Render of each Context.HTML call, implement it to
+// plug in another template engine
+type HTMLRender interface {
+	Instance(name string, data interface{}) Render
+}
+
+// HTMLProduction renders the templates of a single parsed set
+type HTMLProduction struct {
+	Template *template.Template
+}
+
+func (r HTMLProduction) Instance(name string, data interface{}) Render {
+	return HTML{Template: r.Template, Name: name, Data: data}
+}
+
+// HTMLPages holds one template set per page, each page parsed with its
+// layouts and partials, so pages can redefine the same {{block}}s. A page
+// is rendered by executing the root template of its set
+type HTMLPages map[string]*template.Template
+
+func (r HTMLPages) Instance(name string, data interface{}) Render {
+	t, ok := r[name]
+	if !ok {
+		// rendered as a "not loaded" error
+		return HTML{Name: name}
+	}
+	return HTML{Template: t, Data: data}
+}
+
+// HTML executes the template Name of Template with Data,
+// or Template itself when Name is empty
+type HTML struct {
+	Template *template.Template
+	Name     string
+	Data     interface{}
+}
+
+func (r HTML) Render(w io.Writer) error {
+	if r.Template == nil {
+		if r.Name != "" {
+			return fmt.Errorf("render: html template %q is not loaded", r.Name)
+		}
+		return errors.New("render: html templates are not loaded")
+	}
+	if r.Name == "" {
+		return r.Template.Execute(w, r.Data)
+	}
+	return r.Template.ExecuteTemplate(w, r.Name, r.Data)
+}
+
+func (r HTML) ContentType() string {
+	return "text/html; charset=utf-8"
+}
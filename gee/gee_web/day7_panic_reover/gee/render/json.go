@@ -0,0 +1,134 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+)
+
+const (
+	jsonContentType       = "application/json; charset=utf-8"
+	javascriptContentType = "application/javascript; charset=utf-8"
+)
+
+// JSON encodes Data as compact JSON
+type JSON struct {
+	Data interface{}
+}
+
+func (r JSON) Render(w io.Writer) error {
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r JSON) ContentType() string {
+	return jsonContentType
+}
+
+// IndentedJSON encodes Data as human readable JSON
+type IndentedJSON struct {
+	Data interface{}
+}
+
+func (r IndentedJSON) Render(w io.Writer) error {
+	b, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r IndentedJSON) ContentType() string {
+	return jsonContentType
+}
+
+// SecureJSON prefixes JSON arrays with Prefix to prevent JSON hijacking
+type SecureJSON struct {
+	Prefix string
+	Data   interface{}
+}
+
+func (r SecureJSON) Render(w io.Writer) error {
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(b, []byte("[")) && bytes.HasSuffix(b, []byte("]")) {
+		if _, err = io.WriteString(w, r.Prefix); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r SecureJSON) ContentType() string {
+	return jsonContentType
+}
+
+// PureJSON encodes Data as JSON without escaping HTML characters
+type PureJSON struct {
+	Data interface{}
+}
+
+func (r PureJSON) Render(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r.Data)
+}
+
+func (r PureJSON) ContentType() string {
+	return jsonContentType
+}
+
+// ErrInvalidCallback is returned by JSONP for a Callback that isn't a
+// JavaScript identifier such as cb or jQuery.cb_1
+var ErrInvalidCallback = errors.New("render: invalid JSONP callback")
+
+var callbackPattern = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$.]*$`)
+
+// ValidCallback reports whether callback can be used as a JSONP callback
+func ValidCallback(callback string) bool {
+	return callbackPattern.MatchString(callback)
+}
+
+// JSONP wraps JSON in a call to Callback, plain JSON without a callback
+type JSONP struct {
+	Callback string
+	Data     interface{}
+}
+
+func (r JSONP) Render(w io.Writer) error {
+	if r.Callback != "" && !ValidCallback(r.Callback) {
+		return ErrInvalidCallback
+	}
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if r.Callback == "" {
+		_, err = w.Write(b)
+		return err
+	}
+
+	for _, s := range []string{r.Callback, "(", string(b), ");"} {
+		if _, err = io.WriteString(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r JSONP) ContentType() string {
+	if r.Callback == "" {
+		return jsonContentType
+	}
+	return javascriptContentType
+}
@@ -0,0 +1,25 @@
+package render
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoBuf encodes Data in the protocol buffers wire format
+type ProtoBuf struct {
+	Data proto.Message
+}
+
+func (r ProtoBuf) Render(w io.Writer) error {
+	b, err := proto.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r ProtoBuf) ContentType() string {
+	return "application/x-protobuf"
+}
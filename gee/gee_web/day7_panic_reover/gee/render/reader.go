@@ -0,0 +1,21 @@
+package render
+
+import "io"
+
+// Reader streams a body from Reader, it is copied to the response as is
+// instead of being buffered
+type Reader struct {
+	MIME          string
+	ContentLength int64 // -1 when unknown
+	Headers       map[string]string
+	Reader        io.Reader
+}
+
+func (r Reader) Render(w io.Writer) error {
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
+
+func (r Reader) ContentType() string {
+	return r.MIME
+}
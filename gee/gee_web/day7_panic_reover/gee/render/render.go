@@ -0,0 +1,32 @@
+// Package render holds the response body encoders used by gee.Context.
+package render
+
+import "io"
+
+// Render encodes a response body. Context buffers the output of Render
+// before writing anything, so an encoding error can still become a 500
+type Render interface {
+	// Render writes the encoded body to w
+	Render(w io.Writer) error
+	// ContentType returns the Content-Type header of the body
+	ContentType() string
+}
+
+var (
+	_ Render = JSON{}
+	_ Render = IndentedJSON{}
+	_ Render = SecureJSON{}
+	_ Render = PureJSON{}
+	_ Render = JSONP{}
+	_ Render = XML{}
+	_ Render = YAML{}
+	_ Render = ProtoBuf{}
+	_ Render = Text{}
+	_ Render = Data{}
+	_ Render = Reader{}
+	_ Render = HTML{}
+	_ Render = SSEvent{}
+
+	_ HTMLRender = HTMLProduction{}
+	_ HTMLRender = HTMLPages{}
+)
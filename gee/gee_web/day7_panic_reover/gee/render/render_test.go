@@ -0,0 +1,72 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type xmlUser struct {
+	Name string
+}
+
+func TestRenderers(t *testing.T) {
+	data := map[string]interface{}{"name": "<gee>"}
+	cases := []struct {
+		r           Render
+		body        string
+		contentType string
+	}{
+		{JSON{Data: data}, `{"name":"\u003cgee\u003e"}`, jsonContentType},
+		{IndentedJSON{Data: data}, "{\n    \"name\": \"\\u003cgee\\u003e\"\n}", jsonContentType},
+		{PureJSON{Data: data}, "{\"name\":\"<gee>\"}\n", jsonContentType},
+		{SecureJSON{Prefix: "while(1);", Data: []int{1, 2}}, "while(1);[1,2]", jsonContentType},
+		{SecureJSON{Prefix: "while(1);", Data: data}, `{"name":"\u003cgee\u003e"}`, jsonContentType},
+		{JSONP{Callback: "cb", Data: []int{1}}, "cb([1]);", javascriptContentType},
+		{JSONP{Callback: "jQuery.$cb_1", Data: []int{1}}, "jQuery.$cb_1([1]);", javascriptContentType},
+		{JSONP{Data: []int{1}}, "[1]", jsonContentType},
+		{XML{Data: xmlUser{Name: "gee"}}, "<xmlUser><Name>gee</Name></xmlUser>", "application/xml; charset=utf-8"},
+		{YAML{Data: data}, "name: <gee>\n", "application/x-yaml; charset=utf-8"},
+		{Text{Format: "100%"}, "100%", "text/plain; charset=utf-8"},
+		{Text{Format: "%d%%", Data: []interface{}{100}}, "100%", "text/plain; charset=utf-8"},
+		{Data{MIME: "image/png", Data: []byte("png")}, "png", "image/png"},
+		{SSEvent{Event: "tick", Data: "1"}, "event: tick\ndata: 1\n\n", "text/event-stream"},
+		{SSEvent{ID: "7\nid: 8", Retry: 3000, Data: "a\r\nb"}, "id: 7id: 8\nretry: 3000\ndata: a\ndata: b\n\n", "text/event-stream"},
+		{SSEvent{Data: data}, `data: {"name":"\u003cgee\u003e"}` + "\n\n", "text/event-stream"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := c.r.Render(&buf); err != nil {
+			t.Errorf("%T: unexpected error %v", c.r, err)
+			continue
+		}
+		if buf.String() != c.body {
+			t.Errorf("%T: expected body %q, got %q", c.r, c.body, buf.String())
+		}
+		if c.r.ContentType() != c.contentType {
+			t.Errorf("%T: expected Content-Type %q, got %q", c.r, c.contentType, c.r.ContentType())
+		}
+	}
+}
+
+func TestJSONPInvalidCallback(t *testing.T) {
+	for _, callback := range []string{"alert(document.domain);f", "1cb", "cb</script>", "cb\u2028"} {
+		var buf bytes.Buffer
+		if err := (JSONP{Callback: callback, Data: 1}).Render(&buf); err != ErrInvalidCallback || buf.Len() != 0 {
+			t.Errorf("%q: expected ErrInvalidCallback, got %v %q", callback, err, buf.String())
+		}
+	}
+}
+
+func TestProtoBuf(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (ProtoBuf{Data: wrapperspb.String("gee")}).Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var msg wrapperspb.StringValue
+	if err := proto.Unmarshal(buf.Bytes(), &msg); err != nil || msg.Value != "gee" {
+		t.Fatalf("expected to decode 'gee', got %q, %v", msg.Value, err)
+	}
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SSEvent is one Server-Sent Event. Data is written as is when it is a
+// string or []byte, as JSON otherwise
+type SSEvent struct {
+	Event string
+	ID    string
+	// Retry asks the client to wait Retry milliseconds before reconnecting
+	Retry uint
+	Data  interface{}
+}
+
+// fieldReplacer keeps the event and id fields on a single line
+var fieldReplacer = strings.NewReplacer("\n", "", "\r", "")
+
+func (r SSEvent) Render(w io.Writer) error {
+	var data string
+	switch v := r.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	var buf strings.Builder
+	if r.ID != "" {
+		buf.WriteString("id: " + fieldReplacer.Replace(r.ID) + "\n")
+	}
+	if r.Event != "" {
+		buf.WriteString("event: " + fieldReplacer.Replace(r.Event) + "\n")
+	}
+	if r.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatUint(uint64(r.Retry), 10) + "\n")
+	}
+	// a multi-line payload becomes one data field per line
+	data = strings.Replace(data, "\r\n", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func (r SSEvent) ContentType() string {
+	return "text/event-stream"
+}
@@ -0,0 +1,25 @@
+package render
+
+import (
+	"fmt"
+	"io"
+)
+
+// Text writes Format as plain text, formatted with Data when given
+type Text struct {
+	Format string
+	Data   []interface{}
+}
+
+func (r Text) Render(w io.Writer) (err error) {
+	if len(r.Data) > 0 {
+		_, err = fmt.Fprintf(w, r.Format, r.Data...)
+	} else {
+		_, err = io.WriteString(w, r.Format)
+	}
+	return
+}
+
+func (r Text) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,19 @@
+package render
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// XML encodes Data as XML
+type XML struct {
+	Data interface{}
+}
+
+func (r XML) Render(w io.Writer) error {
+	return xml.NewEncoder(w).Encode(r.Data)
+}
+
+func (r XML) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
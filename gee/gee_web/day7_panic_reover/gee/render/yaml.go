@@ -0,0 +1,25 @@
+package render
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YAML encodes Data as YAML
+type YAML struct {
+	Data interface{}
+}
+
+func (r YAML) Render(w io.Writer) error {
+	b, err := yaml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r YAML) ContentType() string {
+	return "application/x-yaml; charset=utf-8"
+}
@@ -0,0 +1,132 @@
+package gee
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+const (
+	noWritten     = -1
+	defaultStatus = http.StatusOK
+)
+
+// ResponseWriter wraps http.ResponseWriter to track the status code and the
+// number of bytes written. The status line is only sent on the first Write,
+// Flush or WriteHeaderNow, so middlewares can still change it until then
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	http.CloseNotifier
+
+	// Status returns the HTTP status code of the response
+	Status() int
+	// Size returns the number of body bytes written, -1 before the headers
+	Size() int
+	// Written reports whether the headers have been sent
+	Written() bool
+	// WriteString writes s to the body
+	WriteString(s string) (int, error)
+	// WriteHeaderNow sends the headers if they have not been sent yet
+	WriteHeaderNow()
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	size   int
+	status int
+}
+
+var _ ResponseWriter = &responseWriter{}
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.size = noWritten
+	w.status = defaultStatus
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code {
+		if w.Written() {
+			log.Printf("[WARNING] Headers were already written. Wanted to override status code %d with %d", w.status, code)
+			return
+		}
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) WriteString(s string) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+// Flush sends the headers and any buffered data to the client
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, the response then
+// counts as written so gee won't send headers on its own
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("gee: the ResponseWriter doesn't support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && w.size < 0 {
+		w.size = 0
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push, http.ErrNotSupported otherwise
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// CloseNotify implements http.CloseNotifier, prefer Request.Context().Done()
+func (w *responseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
@@ -0,0 +1,69 @@
+package gee
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResponseWriterTracksStatusAndSize(t *testing.T) {
+	var w responseWriter
+	rec := httptest.NewRecorder()
+	w.reset(rec)
+
+	if w.Written() || w.Size() != noWritten || w.Status() != http.StatusOK {
+		t.Fatalf("unexpected initial state: written %v, size %d, status %d", w.Written(), w.Size(), w.Status())
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if w.Written() {
+		t.Fatal("WriteHeader shouldn't send the headers yet")
+	}
+	w.Write([]byte("hello "))
+	w.WriteString("gee")
+	if !w.Written() || w.Size() != 9 || rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected state: written %v, size %d, code %d", w.Written(), w.Size(), rec.Code)
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	w.WriteHeader(http.StatusInternalServerError)
+	if w.Status() != http.StatusCreated || !strings.Contains(logs.String(), "already written") {
+		t.Fatalf("a second WriteHeader should be refused, status %d, logs %q", w.Status(), logs.String())
+	}
+}
+
+func TestResponseWriterFlushAndHijack(t *testing.T) {
+	var w responseWriter
+	rec := httptest.NewRecorder()
+	w.reset(rec)
+
+	w.Flush()
+	if !w.Written() || !rec.Flushed {
+		t.Fatal("Flush should send the headers and flush the recorder")
+	}
+	if _, _, err := w.Hijack(); err == nil {
+		t.Fatal("httptest.ResponseRecorder can't be hijacked")
+	}
+	if err := w.Push("/app.css", nil); err != http.ErrNotSupported {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestStatusWithoutBodyIsSent(t *testing.T) {
+	r := New()
+	r.DELETE("/user/:id", func(ctx *Context) {
+		ctx.Writer.WriteHeader(http.StatusAccepted)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/user/1", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", w.Code)
+	}
+}
@@ -3,20 +3,21 @@ package gee
 import (
 	"log"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
 )
 
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	roots map[string]*node
 }
 
 // root keys eg, roots['GET'] roots['POST']
-// handlers key eg, handlers['GET-/p/:lang/doc'], handlers['POST-/p/book']
+// handler chains live on the node that terminates each pattern
 func newRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+		roots: make(map[string]*node),
 	}
 }
 
@@ -35,56 +36,206 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
-func (r *router) addRoute(method, pattern string, handler HandlerFunc) {
+// addRoute registers pattern with a fully resolved handler chain
+// (group middlewares followed by the route handler)
+// it panics when pattern is ambiguous with, or duplicates, an existing route
+func (r *router) addRoute(method, pattern string, handlers []HandlerFunc) *node {
 	log.Printf("Route %4s - %s", method, pattern)
 
-	parts := parsePattern(pattern)
-	key := method + "-" + pattern
 	_, ok := r.roots[method]
 	if !ok {
 		r.roots[method] = &node{}
 	}
-	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[key] = handler
+	n := r.roots[method].insert(pattern)
+	n.handlers = handlers
+	return n
 }
 
 func (r *router) getRoute(method, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
 	params := make(map[string]string)
+	if n := r.search(method, path, params); n != nil {
+		return n, params
+	}
+	return nil, nil
+}
+
+// search is getRoute filling a caller owned params map, which is left
+// untouched when nothing matches
+func (r *router) search(method, path string, params map[string]string) *node {
 	root, ok := r.roots[method]
 	if !ok {
-		return nil, nil
+		return nil
 	}
+	return root.search(path, params)
+}
 
-	n := root.search(searchParts, 0)
-
-	if n != nil {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
+// allowed lists the methods registered for path, used for the Allow header
+func (r *router) allowed(path string) []string {
+	allow := make([]string, 0, len(r.roots)+2)
+	for method := range r.roots {
+		if method == http.MethodOptions {
+			continue
+		}
+		if n, _ := r.getRoute(method, path); n != nil {
+			allow = append(allow, method)
+		}
+	}
+	if len(allow) == 0 {
+		if n, _ := r.getRoute(http.MethodOptions, path); n == nil {
+			return nil
 		}
-		return n, params
 	}
 
-	return nil, nil
+	hasGet, hasHead := false, false
+	for _, method := range allow {
+		hasGet = hasGet || method == http.MethodGet
+		hasHead = hasHead || method == http.MethodHead
+	}
+	if hasGet && !hasHead {
+		allow = append(allow, http.MethodHead)
+	}
+	allow = append(allow, http.MethodOptions)
+	sort.Strings(allow)
+	return allow
 }
 
 func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
+	n := r.search(c.Method, c.Path, c.Params)
+	if n == nil && c.Method == http.MethodHead {
+		// HEAD falls back to GET, net/http discards the body of HEAD responses
+		n = r.search(http.MethodGet, c.Path, c.Params)
+	}
 	if n != nil {
-		key := c.Method + "-" + n.pattern
-		c.Params = params
-		c.handlers = append(c.handlers, r.handlers[key])
+		c.fullPath = n.pattern
+		c.group = n.group
+		c.handlers = n.handlers
+		c.Next()
+		return
+	}
+
+	engine := c.engine
+	if c.Method != http.MethodConnect && c.Path != "/" {
+		if target, ok := r.redirectTarget(c); ok {
+			c.handlers = engine.combineHandlers(func(ctx *Context) {
+				redirectRequest(ctx, target)
+			})
+			c.Next()
+			return
+		}
+	}
+
+	// unmatched requests run the middlewares of the group owning the path,
+	// so e.g. an authenticated group doesn't reveal which paths exist
+	group := engine.groupFor(c.Path)
+	var handlers []HandlerFunc
+	if allow := r.allowed(c.Path); len(allow) > 0 {
+		c.SetHeader("Allow", strings.Join(allow, ", "))
+		switch {
+		case c.Method == http.MethodOptions:
+			handlers = []HandlerFunc{func(ctx *Context) {
+				ctx.Status(http.StatusNoContent)
+			}}
+		case len(engine.noMethod) > 0:
+			handlers = engine.noMethod
+		default:
+			handlers = []HandlerFunc{defaultNoMethod}
+		}
+	} else if len(engine.noRoute) > 0 {
+		handlers = engine.noRoute
 	} else {
-		c.handlers = append(c.handlers, func(ctx *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		handlers = []HandlerFunc{defaultNoRoute}
 	}
+	c.group = group
+	c.handlers = group.combineHandlers(handlers...)
 	c.Next()
 }
+
+func defaultNoRoute(ctx *Context) {
+	ctx.String(http.StatusNotFound, "404 NOT FOUND: %s\n", ctx.Path)
+}
+
+func defaultNoMethod(ctx *Context) {
+	ctx.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", ctx.Method, ctx.Path)
+}
+
+// exists reports whether a route matches method and path, a GET route
+// also matching HEAD
+func (r *router) exists(method, path string) bool {
+	params := make(map[string]string)
+	if r.search(method, path, params) != nil {
+		return true
+	}
+	return method == http.MethodHead && r.search(http.MethodGet, path, params) != nil
+}
+
+// existsFold is exists ignoring case, it returns the path as registered
+func (r *router) existsFold(method, path string) (string, bool) {
+	for _, m := range []string{method, http.MethodGet} {
+		if root, ok := r.roots[m]; ok {
+			if fixed, ok := root.searchFold(path); ok {
+				return fixed, true
+			}
+		}
+		if method != http.MethodHead {
+			break
+		}
+	}
+	return "", false
+}
+
+// redirectTarget returns the path an unmatched request should be redirected
+// to, following RedirectTrailingSlash and RedirectFixedPath
+func (r *router) redirectTarget(c *Context) (string, bool) {
+	engine := c.engine
+	if engine.RedirectTrailingSlash {
+		if alt := toggleTrailingSlash(c.Path); r.exists(c.Method, alt) {
+			return alt, true
+		}
+	}
+	if !engine.RedirectFixedPath {
+		return "", false
+	}
+
+	cleaned := cleanPath(c.Path)
+	candidates := []string{cleaned}
+	if engine.RedirectTrailingSlash && cleaned != "/" {
+		candidates = append(candidates, toggleTrailingSlash(cleaned))
+	}
+	for _, candidate := range candidates {
+		if fixed, ok := r.existsFold(c.Method, candidate); ok && fixed != c.Path {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+func toggleTrailingSlash(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
+
+// cleanPath removes . and .. elements and repeated slashes, keeping the
+// trailing slash
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// redirectRequest redirects permanently, with 308 for methods other than
+// GET and HEAD so the client keeps the method and body
+func redirectRequest(ctx *Context, target string) {
+	code := http.StatusMovedPermanently
+	if ctx.Method != http.MethodGet && ctx.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	// never "//host", which clients read as another host
+	target = "/" + strings.TrimLeft(target, "/")
+	location := (&url.URL{Path: target, RawQuery: ctx.Req.URL.RawQuery}).String()
+	ctx.SetHeader("Location", location)
+	ctx.Status(code)
+}
@@ -2,7 +2,10 @@ package gee
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -43,3 +46,291 @@ func TestGetRoute(t *testing.T) {
 
 	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["name"])
 }
+
+func TestGroupMiddlewareResolvedAtRegistration(t *testing.T) {
+	var called []string
+	mark := func(name string) HandlerFunc {
+		return func(ctx *Context) {
+			called = append(called, name)
+		}
+	}
+
+	r := New()
+	r.Use(mark("engine"))
+	v1 := r.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.GET("/hello", mark("v1 handler"))
+	v10 := r.Group("/v10")
+	v10.GET("/hello", mark("v10 handler"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v10/hello", nil))
+	if !reflect.DeepEqual(called, []string{"engine", "v10 handler"}) {
+		t.Fatalf("/v10/hello should not run /v1 middlewares, got %v", called)
+	}
+
+	called = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/hello", nil))
+	if !reflect.DeepEqual(called, []string{"engine", "v1", "v1 handler"}) {
+		t.Fatalf("/v1/hello should run engine and v1 middlewares, got %v", called)
+	}
+}
+
+func TestMethodFallbacks(t *testing.T) {
+	r := New()
+	r.GET("/user/:id", func(ctx *Context) {
+		ctx.String(http.StatusOK, "user %s", ctx.Param("id"))
+	})
+	r.PUT("/user/:id", func(ctx *Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	r.Any("/any", func(ctx *Context) {
+		ctx.String(http.StatusOK, ctx.Method)
+	})
+
+	cases := []struct {
+		method, path string
+		code         int
+		allow        string
+	}{
+		{"GET", "/user/1", http.StatusOK, ""},
+		{"HEAD", "/user/1", http.StatusOK, ""},
+		{"PUT", "/user/1", http.StatusNoContent, ""},
+		{"OPTIONS", "/user/1", http.StatusNoContent, "GET, HEAD, OPTIONS, PUT"},
+		{"DELETE", "/user/1", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, PUT"},
+		{"PATCH", "/any", http.StatusOK, ""},
+		{"DELETE", "/any", http.StatusOK, ""},
+		{"GET", "/missing", http.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Code != c.code {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.code, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != c.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", c.method, c.path, c.allow, allow)
+		}
+	}
+}
+
+func newLargeTestRouter() *router {
+	r := newRouter()
+	for i := 0; i < 200; i++ {
+		r.addRoute("GET", fmt.Sprintf("/api/v1/resource%d", i), nil)
+		r.addRoute("GET", fmt.Sprintf("/api/v1/resource%d/:id", i), nil)
+		r.addRoute("GET", fmt.Sprintf("/api/v1/resource%d/:id/items/:item", i), nil)
+		r.addRoute("GET", fmt.Sprintf("/static%d/*filepath", i), nil)
+	}
+	return r
+}
+
+func BenchmarkGetRoute(b *testing.B) {
+	r := newLargeTestRouter()
+	paths := []string{
+		"/api/v1/resource0",
+		"/api/v1/resource100/42",
+		"/api/v1/resource199/42/items/7",
+		"/static150/css/site.css",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if n, _ := r.getRoute("GET", paths[i%len(paths)]); n == nil {
+			b.Fatal("route not found")
+		}
+	}
+}
+
+func TestRoutePriority(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/files/*filepath", nil)
+	r.addRoute("GET", "/hello/:name/c", nil)
+	r.addRoute("GET", "/hello/:name", nil)
+	r.addRoute("GET", "/hello/b/c", nil)
+	r.addRoute("GET", "/files/:name/raw", nil)
+
+	cases := []struct {
+		path, pattern string
+		params        map[string]string
+	}{
+		{"/hello/b/c", "/hello/b/c", map[string]string{}},
+		{"/hello/b", "/hello/:name", map[string]string{"name": "b"}},
+		{"/hello/x/c", "/hello/:name/c", map[string]string{"name": "x"}},
+		{"/files/a/raw", "/files/:name/raw", map[string]string{"name": "a"}},
+		{"/files/a/raw/b", "/files/*filepath", map[string]string{"filepath": "a/raw/b"}},
+		{"/files/", "/files/*filepath", map[string]string{"filepath": ""}},
+	}
+	for _, c := range cases {
+		n, ps := r.getRoute("GET", c.path)
+		if n == nil || n.pattern != c.pattern {
+			t.Errorf("%s should match %s, got %v", c.path, c.pattern, n)
+			continue
+		}
+		if !reflect.DeepEqual(ps, c.params) {
+			t.Errorf("%s: expected params %v, got %v", c.path, c.params, ps)
+		}
+	}
+
+	if n, _ := r.getRoute("GET", "/hello/b/c/d"); n != nil {
+		t.Errorf("/hello/b/c/d shouldn't match, got %s", n.pattern)
+	}
+}
+
+func TestRouteConstraints(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/user/:name", nil)
+	r.addRoute("GET", "/user/:id<int>", nil)
+	r.addRoute("GET", "/user/:uid<uuid>/posts", nil)
+	r.addRoute("GET", "/file/:name<[a-z]+\\.txt>", nil)
+	r.addRoute("GET", "/file/:id<int>/raw", nil)
+
+	cases := []struct {
+		path, pattern string
+		params        map[string]string
+	}{
+		{"/user/42", "/user/:id<int>", map[string]string{"id": "42"}},
+		{"/user/-1", "/user/:id<int>", map[string]string{"id": "-1"}},
+		{"/user/bob", "/user/:name", map[string]string{"name": "bob"}},
+		{"/user/42x", "/user/:name", map[string]string{"name": "42x"}},
+		{"/user/3F2504E0-4F89-11D3-9A0C-0305E82C3301/posts", "/user/:uid<uuid>/posts",
+			map[string]string{"uid": "3F2504E0-4F89-11D3-9A0C-0305E82C3301"}},
+		{"/file/a.txt", "/file/:name<[a-z]+\\.txt>", map[string]string{"name": "a.txt"}},
+		{"/file/7/raw", "/file/:id<int>/raw", map[string]string{"id": "7"}},
+	}
+	for _, c := range cases {
+		n, ps := r.getRoute("GET", c.path)
+		if n == nil || n.pattern != c.pattern {
+			t.Errorf("%s should match %s, got %v", c.path, c.pattern, n)
+			continue
+		}
+		if !reflect.DeepEqual(ps, c.params) {
+			t.Errorf("%s: expected params %v, got %v", c.path, c.params, ps)
+		}
+	}
+
+	for _, path := range []string{"/user/bob/posts", "/file/a.md", "/file/x/raw"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Errorf("%s shouldn't match, got %s", path, n.pattern)
+		}
+	}
+}
+
+func TestRouteConflicts(t *testing.T) {
+	conflicts := [][]string{
+		{"/p/:lang", "/p/:id"},
+		{"/p/:lang/doc", "/p/:id/intro"},
+		{"/assets/*filepath", "/assets/*name"},
+		{"/p/:id<int>", "/p/:lang", "/p/:name"},
+		{"/p/:id<[0-9>"},
+		{"/p/:id<int"},
+		{"/p/:<int>"},
+		{"/p/:id<>"},
+		{"/u/:id<int>", "/u/:n<int>"},
+		{"/u/:id<int>/a", "/u/:n<-?[0-9]+>/b"},
+		{"/hello", "/hello"},
+		{"/assets/*filepath/more"},
+		{"/:"},
+		{"hello"},
+	}
+	for _, patterns := range conflicts {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %v should panic", patterns)
+				}
+			}()
+			r := newRouter()
+			for _, pattern := range patterns {
+				r.addRoute("GET", pattern, nil)
+			}
+		}()
+	}
+}
+
+func TestNoRouteAndNoMethod(t *testing.T) {
+	r := New()
+	r.Use(func(ctx *Context) {
+		ctx.SetHeader("X-Engine", "1")
+		ctx.Next()
+	})
+	admin := r.Group("/admin")
+	admin.Use(func(ctx *Context) {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+	})
+	admin.GET("/users", func(ctx *Context) {})
+	r.GET("/about", func(ctx *Context) {})
+	r.NoRoute(func(ctx *Context) {
+		ctx.JSON(http.StatusNotFound, H{"message": "no route", "path": ctx.Path})
+	})
+
+	cases := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/missing", http.StatusNotFound, `{"message":"no route","path":"/missing"}`},
+		{"GET", "/admin/missing", http.StatusUnauthorized, ""},
+		{"GET", "/administrator", http.StatusNotFound, `{"message":"no route","path":"/administrator"}`},
+		{"POST", "/about", http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: POST /about\n"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Code != c.code || w.Body.String() != c.body || w.Header().Get("X-Engine") != "1" {
+			t.Errorf("%s %s: expected %d %q, got %d %q %v", c.method, c.path, c.code, c.body, w.Code, w.Body.String(), w.Header())
+		}
+	}
+
+	r.NoMethod(func(ctx *Context) {
+		ctx.String(http.StatusMethodNotAllowed, "allowed: %s", ctx.Writer.Header().Get("Allow"))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/about", nil))
+	if w.Body.String() != "allowed: GET, HEAD, OPTIONS" {
+		t.Fatalf("unexpected NoMethod response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	r.GET("/about", func(ctx *Context) {})
+	r.POST("/users/", func(ctx *Context) {})
+	r.GET("/user/:name/profile", func(ctx *Context) {})
+
+	cases := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{"GET", "/about/?lang=go", http.StatusMovedPermanently, "/about?lang=go"},
+		{"POST", "/users", http.StatusPermanentRedirect, "/users/"},
+		{"GET", "/ABOUT", http.StatusMovedPermanently, "/about"},
+		{"GET", "/docs/../about", http.StatusMovedPermanently, "/about"},
+		{"GET", "//about", http.StatusMovedPermanently, "/about"},
+		{"GET", "/USER/Geek/Profile/", http.StatusMovedPermanently, "/user/Geek/profile"},
+		{"GET", "/missing/", http.StatusNotFound, ""},
+		{"GET", "/about", http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/", nil)
+		req.URL.Path, req.URL.RawQuery = c.path, ""
+		if i := strings.IndexByte(c.path, '?'); i >= 0 {
+			req.URL.Path, req.URL.RawQuery = c.path[:i], c.path[i+1:]
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code || w.Header().Get("Location") != c.location {
+			t.Errorf("%s %s: expected %d %q, got %d %q", c.method, c.path, c.code, c.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	r.RedirectTrailingSlash, r.RedirectFixedPath = false, false
+	for _, path := range []string{"/about/", "/ABOUT"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: redirects are disabled, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Route is a registered route, returned by GET, POST... to name it
+type Route struct {
+	Method string
+	Path   string
+
+	name   string
+	node   *node
+	engine *Engine
+}
+
+// Name names the route for Engine.URL, e.g.
+//
+//	r.GET("/user/:id", showUser).Name("user.show")
+//
+// It panics when the name is already taken
+func (r *Route) Name(name string) *Route {
+	engine := r.engine
+	if existing, ok := engine.named[name]; ok {
+		panic(fmt.Sprintf("gee: route name '%s' is already used by %s %s", name, existing.Method, existing.Path))
+	}
+	if engine.named == nil {
+		engine.named = make(map[string]*Route)
+	}
+	r.name = name
+	engine.named[name] = r
+	return r
+}
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Name    string `json:"name,omitempty"`
+	Handler string `json:"handler"`
+	// Middlewares is the number of handlers running before Handler
+	Middlewares int `json:"middlewares"`
+}
+
+// Routes returns the registered routes in registration order
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(engine.routes))
+	for _, r := range engine.routes {
+		handlers := r.node.handlers
+		routes = append(routes, RouteInfo{
+			Method:      r.Method,
+			Path:        r.Path,
+			Name:        r.name,
+			Handler:     nameOfFunction(handlers[len(handlers)-1]),
+			Middlewares: len(handlers) - 1,
+		})
+	}
+	return routes
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// RoutesHandler responds with Routes as JSON, e.g. for ops tooling:
+//
+//	r.GET("/debug/routes", r.RoutesHandler())
+func (engine *Engine) RoutesHandler() HandlerFunc {
+	return func(ctx *Context) {
+		ctx.JSON(http.StatusOK, engine.Routes())
+	}
+}
+
+// URL builds the path of the route named name, filling its :param and
+// *catchall segments from params. Parameters are escaped, the slashes of
+// a catch-all value are kept, a :param must satisfy its constraint
+func (engine *Engine) URL(name string, params map[string]string) (string, error) {
+	r, ok := engine.named[name]
+	if !ok {
+		return "", fmt.Errorf("gee: no route named '%s'", name)
+	}
+
+	parts := strings.Split(r.Path, "/")
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			continue
+		}
+		key, constraint, _ := parseParam(part)
+		value, ok := params[key]
+		if !ok {
+			return "", fmt.Errorf("gee: route '%s' needs the parameter '%s'", name, key)
+		}
+		if part[0] == ':' {
+			if value == "" {
+				return "", fmt.Errorf("gee: parameter '%s' of route '%s' can't be empty", key, name)
+			}
+			if constraint != nil && !constraint.MatchString(value) {
+				return "", fmt.Errorf("gee: parameter '%s' of route '%s' doesn't match '%s'", key, name, part)
+			}
+			parts[i] = url.PathEscape(value)
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for j, segment := range segments {
+			segments[j] = url.PathEscape(segment)
+		}
+		parts[i] = strings.Join(segments, "/")
+	}
+	return strings.Join(parts, "/"), nil
+}
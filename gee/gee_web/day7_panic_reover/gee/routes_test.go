@@ -0,0 +1,84 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func showUser(ctx *Context) {}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(Logger())
+	api := r.Group("/api")
+	api.Use(Recovery())
+	api.GET("/users/:id", showUser).Name("user.show")
+	r.POST("/login", func(ctx *Context) {})
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", routes)
+	}
+	user := routes[0]
+	if user.Method != "GET" || user.Path != "/api/users/:id" || user.Name != "user.show" ||
+		user.Handler != "gee.showUser" || user.Middlewares != 2 {
+		t.Fatalf("unexpected route %+v", user)
+	}
+	if login := routes[1]; login.Middlewares != 1 || !strings.HasPrefix(login.Handler, "gee.TestRoutes") {
+		t.Fatalf("unexpected route %+v", login)
+	}
+
+	r.GET("/debug/routes", r.RoutesHandler())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes", nil))
+	var listed []RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil || len(listed) != 3 || listed[0] != user {
+		t.Fatalf("unexpected /debug/routes %q %v", w.Body.String(), err)
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := New()
+	r.GET("/users/:id/posts/:post", showUser).Name("post.show")
+	r.GET("/files/*filepath", showUser).Name("file")
+	r.GET("/about", showUser).Name("about")
+	r.GET("/orders/:id<int>", showUser).Name("order")
+	r.Any("/ping/:id", showUser)[0].Name("ping")
+
+	cases := []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{
+		{"post.show", map[string]string{"id": "7", "post": "hello world"}, "/users/7/posts/hello%20world"},
+		{"file", map[string]string{"filepath": "css/a b.css"}, "/files/css/a%20b.css"},
+		{"file", map[string]string{"filepath": ""}, "/files/"},
+		{"about", nil, "/about"},
+		{"order", map[string]string{"id": "12"}, "/orders/12"},
+		{"ping", map[string]string{"id": "1"}, "/ping/1"},
+	}
+	for _, c := range cases {
+		if url, err := r.URL(c.name, c.params); err != nil || url != c.expected {
+			t.Errorf("%s %v: expected %s, got %s %v", c.name, c.params, c.expected, url, err)
+		}
+	}
+
+	for name, params := range map[string]map[string]string{
+		"post.show": {"id": "7"},
+		"missing":   nil,
+		"order":     {"id": "twelve"},
+	} {
+		if _, err := r.URL(name, params); err == nil {
+			t.Errorf("%s %v: expected an error", name, params)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("a duplicate route name should panic")
+		}
+	}()
+	r.POST("/about", showUser).Name("about")
+}
@@ -0,0 +1,78 @@
+package gee
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// Server returns the http.Server shared by all Run methods, it is created
+// on first use with the engine timeouts
+func (engine *Engine) Server() *http.Server {
+	engine.serverOnce.Do(func() {
+		engine.server = &http.Server{
+			Handler:           engine,
+			ReadTimeout:       engine.ReadTimeout,
+			ReadHeaderTimeout: engine.ReadHeaderTimeout,
+			WriteTimeout:      engine.WriteTimeout,
+			IdleTimeout:       engine.IdleTimeout,
+		}
+	})
+	return engine.server
+}
+
+// Run defines the method to start a http server （定义http启动方法)）
+// it blocks until the server fails or Shutdown is called, which makes it
+// return http.ErrServerClosed
+func (engine *Engine) Run(addr string) (err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return engine.RunListener(listener)
+}
+
+// RunTLS starts a https server with the given certificate and key files
+func (engine *Engine) RunTLS(addr, certFile, keyFile string) (err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return engine.Server().ServeTLS(listener, certFile, keyFile)
+}
+
+// RunUnix starts a http server on the unix socket file, a stale socket
+// file left by a previous run is removed first. Any other existing file
+// is an error
+func (engine *Engine) RunUnix(file string) (err error) {
+	if info, err := os.Lstat(file); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("gee: %s exists and is not a unix socket", file)
+		}
+		if err = os.Remove(file); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return engine.RunListener(listener)
+}
+
+// RunListener serves http requests accepted by listener
+func (engine *Engine) RunListener(listener net.Listener) (err error) {
+	return engine.Server().Serve(listener)
+}
+
+// Shutdown stops every Run method from accepting new connections and
+// waits for the active requests to finish, or for ctx to be done.
+// Call it on SIGTERM to deploy without dropping in-flight requests
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	return engine.Server().Shutdown(ctx)
+}
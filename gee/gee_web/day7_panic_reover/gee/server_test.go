@@ -0,0 +1,200 @@
+package gee
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert generates a certificate for 127.0.0.1 in dir
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"gee"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err = ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// waitForServer retries get until the server started in a goroutine answers
+func waitForServer(t *testing.T, get func() (*http.Response, error)) *http.Response {
+	var err error
+	for i := 0; i < 100; i++ {
+		var resp *http.Response
+		if resp, err = get(); err == nil {
+			return resp
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(err)
+	return nil
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestRunTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+	certPEM, _ := ioutil.ReadFile(certFile)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+
+	r := New()
+	r.GET("/", func(ctx *Context) {
+		ctx.String(http.StatusOK, "tls")
+	})
+	addr := freeAddr(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.RunTLS(addr, certFile, keyFile)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp := waitForServer(t, func() (*http.Response, error) {
+		return client.Get("https://" + addr + "/")
+	})
+	if body := readBody(t, resp); body != "tls" {
+		t.Fatalf("expected 'tls', got %q", body)
+	}
+
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Fatalf("expected ErrServerClosed, got %v", err)
+	}
+}
+
+func TestRunUnix(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gee.sock")
+	r := New()
+	r.GET("/", func(ctx *Context) {
+		ctx.String(http.StatusOK, "unix")
+	})
+	go r.RunUnix(file)
+	defer r.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", file)
+		},
+	}}
+	resp := waitForServer(t, func() (*http.Response, error) {
+		return client.Get("http://gee/")
+	})
+	if body := readBody(t, resp); body != "unix" {
+		t.Fatalf("expected 'unix', got %q", body)
+	}
+}
+
+func TestRunUnixKeepsRegularFiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gee.conf")
+	if err := ioutil.WriteFile(file, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().RunUnix(file); err == nil {
+		t.Fatal("expected an error for a regular file")
+	}
+	if b, err := ioutil.ReadFile(file); err != nil || string(b) != "keep" {
+		t.Fatalf("the regular file should be left alone, got %q %v", b, err)
+	}
+}
+
+func TestShutdownDrainsActiveRequests(t *testing.T) {
+	started := make(chan struct{})
+	r := New()
+	r.WriteTimeout = time.Second
+	r.GET("/slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+	if r.Server().WriteTimeout != time.Second {
+		t.Fatal("the server should use the engine timeouts")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- r.RunListener(listener)
+	}()
+
+	respc := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			respc <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		respc <- string(body)
+	}()
+
+	<-started
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-respc; body != "done" {
+		t.Fatalf("the in-flight request should complete, got %q", body)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Fatalf("expected ErrServerClosed, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrInvalidCookie is returned when a cookie was tampered with, or was
+// encoded with a key that has been retired
+var ErrInvalidCookie = errors.New("sessions: invalid cookie")
+
+// Codec protects cookie values. The cookie name is bound to the value,
+// so a value can't be replayed under another cookie name
+type Codec interface {
+	Encode(name string, value []byte) (string, error)
+	Decode(name, value string) ([]byte, error)
+}
+
+var b64 = base64.RawURLEncoding
+
+type signedCodec struct {
+	keys [][]byte
+}
+
+// NewSignedCodec returns a Codec that signs values with HMAC-SHA256, values
+// stay readable by the client. The first key signs, all of them verify, so
+// keys can be rotated by prepending the new one
+func NewSignedCodec(keys ...[]byte) (Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("sessions: at least one key is needed")
+	}
+	for _, key := range keys {
+		if len(key) < 16 {
+			return nil, errors.New("sessions: signing keys need at least 16 bytes")
+		}
+	}
+	return &signedCodec{keys: keys}, nil
+}
+
+func (c *signedCodec) mac(key []byte, name, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "|" + payload))
+	return mac.Sum(nil)
+}
+
+func (c *signedCodec) Encode(name string, value []byte) (string, error) {
+	payload := b64.EncodeToString(value)
+	return payload + "." + b64.EncodeToString(c.mac(c.keys[0], name, payload)), nil
+}
+
+func (c *signedCodec) Decode(name, value string) ([]byte, error) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil, ErrInvalidCookie
+	}
+	payload := value[:i]
+	signature, err := b64.DecodeString(value[i+1:])
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	for _, key := range c.keys {
+		if hmac.Equal(signature, c.mac(key, name, payload)) {
+			data, err := b64.DecodeString(payload)
+			if err != nil {
+				return nil, ErrInvalidCookie
+			}
+			return data, nil
+		}
+	}
+	return nil, ErrInvalidCookie
+}
+
+type encryptedCodec struct {
+	aeads []cipher.AEAD
+}
+
+// NewEncryptedCodec returns a Codec that encrypts and authenticates values
+// with AES-GCM, keys must be 16, 24 or 32 bytes. The first key encrypts,
+// all of them decrypt, so keys can be rotated by prepending the new one
+func NewEncryptedCodec(keys ...[]byte) (Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("sessions: at least one key is needed")
+	}
+	c := &encryptedCodec{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.aeads = append(c.aeads, aead)
+	}
+	return c, nil
+}
+
+func (c *encryptedCodec) Encode(name string, value []byte) (string, error) {
+	aead := c.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(aead.Seal(nonce, nonce, value, []byte(name))), nil
+}
+
+func (c *encryptedCodec) Decode(name, value string) ([]byte, error) {
+	data, err := b64.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	for _, aead := range c.aeads {
+		if len(data) < aead.NonceSize() {
+			return nil, ErrInvalidCookie
+		}
+		nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+		if plain, err := aead.Open(nil, nonce, sealed, []byte(name)); err == nil {
+			return plain, nil
+		}
+	}
+	return nil, ErrInvalidCookie
+}
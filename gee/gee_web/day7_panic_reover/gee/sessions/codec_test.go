@@ -0,0 +1,47 @@
+package sessions
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCodecs(t *testing.T) {
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("fedcba9876543210")
+
+	constructors := map[string]func(keys ...[]byte) (Codec, error){
+		"signed":    NewSignedCodec,
+		"encrypted": NewEncryptedCodec,
+	}
+	for kind, newCodec := range constructors {
+		old, err := newCodec(oldKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rotated, _ := newCodec(newKey, oldKey)
+		retired, _ := newCodec(newKey)
+
+		value, _ := old.Encode("session", []byte("geektutu"))
+		if got, err := rotated.Decode("session", value); err != nil || !bytes.Equal(got, []byte("geektutu")) {
+			t.Errorf("%s: rotated codec should decode old values, got %q %v", kind, got, err)
+		}
+		if _, err := retired.Decode("session", value); err != ErrInvalidCookie {
+			t.Errorf("%s: a retired key should be rejected, got %v", kind, err)
+		}
+		if _, err := old.Decode("other", value); err != ErrInvalidCookie {
+			t.Errorf("%s: a value moved to another cookie should be rejected, got %v", kind, err)
+		}
+		tampered := []byte(value)
+		tampered[0] ^= 1
+		if _, err := old.Decode("session", string(tampered)); err != ErrInvalidCookie {
+			t.Errorf("%s: a tampered value should be rejected, got %v", kind, err)
+		}
+	}
+
+	if _, err := NewEncryptedCodec([]byte("short")); err == nil {
+		t.Error("AES keys must be 16, 24 or 32 bytes")
+	}
+	if _, err := NewSignedCodec(); err == nil {
+		t.Error("a codec needs a key")
+	}
+}
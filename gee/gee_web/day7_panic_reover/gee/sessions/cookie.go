@@ -0,0 +1,90 @@
+package sessions
+
+import (
+	"errors"
+	"gee"
+	"time"
+)
+
+// ErrCookieTooLarge is returned when a session doesn't fit in a cookie
+var ErrCookieTooLarge = errors.New("sessions: cookie larger than 4096 bytes")
+
+// maxCookieSize is the limit browsers are required to support
+const maxCookieSize = 4096
+
+// CookieStore keeps the whole session in the cookie, protected by Codec.
+// The expiry is encoded too, so an old cookie kept by the client is
+// rejected once it expired
+type CookieStore struct {
+	Codec   Codec
+	Options Options
+
+	now func() time.Time
+}
+
+var _ Store = &CookieStore{}
+
+type cookieData struct {
+	Values  map[string]interface{}
+	Expires int64
+}
+
+// NewCookieStore returns a CookieStore whose sessions last 30 days
+func NewCookieStore(codec Codec) *CookieStore {
+	return &CookieStore{
+		Codec:   codec,
+		Options: defaultOptions(30 * 24 * time.Hour),
+		now:     time.Now,
+	}
+}
+
+// Get implements Store
+func (st *CookieStore) Get(ctx *gee.Context, name string) (*Session, error) {
+	s := NewSession(ctx, st, name, st.Options)
+	cookie, err := ctx.Req.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	raw, err := st.Codec.Decode(name, cookie.Value)
+	if err != nil {
+		return s, err
+	}
+	var data cookieData
+	if err := decodeValues(raw, &data); err != nil {
+		return s, err
+	}
+	if data.Expires != 0 && st.now().Unix() >= data.Expires {
+		return s, nil
+	}
+	if data.Values != nil {
+		s.Values = data.Values
+	}
+	s.IsNew = false
+	return s, nil
+}
+
+// Save implements Store
+func (st *CookieStore) Save(ctx *gee.Context, s *Session) error {
+	if s.Options.MaxAge < 0 {
+		setCookie(ctx, s.name, "", s.Options)
+		return nil
+	}
+
+	data := cookieData{Values: s.Values}
+	if s.Options.MaxAge > 0 {
+		data.Expires = st.now().Unix() + int64(s.Options.MaxAge)
+	}
+	raw, err := encodeValues(data)
+	if err != nil {
+		return err
+	}
+	value, err := st.Codec.Encode(s.name, raw)
+	if err != nil {
+		return err
+	}
+	if len(s.name)+len(value) > maxCookieSize {
+		return ErrCookieTooLarge
+	}
+	setCookie(ctx, s.name, value, s.Options)
+	return nil
+}
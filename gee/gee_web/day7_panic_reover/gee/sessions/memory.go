@@ -0,0 +1,126 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"gee"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in the process, the cookie only holds a
+// random session ID. Sessions expire ttl after their last Save, expired
+// ones are dropped lazily
+type MemoryStore struct {
+	Options Options
+
+	ttl       time.Duration
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+var _ Store = &MemoryStore{}
+
+type memorySession struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+// NewMemoryStore returns a MemoryStore whose sessions expire after ttl
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		panic("sessions: ttl must be positive")
+	}
+	return &MemoryStore{
+		Options:  defaultOptions(ttl),
+		ttl:      ttl,
+		sessions: make(map[string]memorySession),
+		now:      time.Now,
+	}
+}
+
+// Get implements Store
+func (st *MemoryStore) Get(ctx *gee.Context, name string) (*Session, error) {
+	s := NewSession(ctx, st, name, st.Options)
+	id, err := ctx.Cookie(name)
+	if err != nil || id == "" {
+		return s, nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stored, ok := st.sessions[id]
+	if !ok {
+		return s, nil
+	}
+	if !st.now().Before(stored.expires) {
+		delete(st.sessions, id)
+		return s, nil
+	}
+	// copy, concurrent requests of a client must not share the map
+	for key, value := range stored.values {
+		s.Values[key] = value
+	}
+	s.ID = id
+	s.IsNew = false
+	return s, nil
+}
+
+// Save implements Store
+func (st *MemoryStore) Save(ctx *gee.Context, s *Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if s.Options.MaxAge < 0 {
+		delete(st.sessions, s.ID)
+		setCookie(ctx, s.name, "", s.Options)
+		return nil
+	}
+	if s.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		s.ID = id
+	}
+
+	now := st.now()
+	st.sweep(now)
+	values := make(map[string]interface{}, len(s.Values))
+	for key, value := range s.Values {
+		values[key] = value
+	}
+	st.sessions[s.ID] = memorySession{values: values, expires: now.Add(st.ttl)}
+	setCookie(ctx, s.name, s.ID, s.Options)
+	return nil
+}
+
+// Len returns the number of sessions held, expired ones included
+func (st *MemoryStore) Len() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.sessions)
+}
+
+// sweep drops the expired sessions, at most once per ttl
+func (st *MemoryStore) sweep(now time.Time) {
+	if now.Sub(st.lastSweep) < st.ttl {
+		return
+	}
+	st.lastSweep = now
+	for id, stored := range st.sessions {
+		if !now.Before(stored.expires) {
+			delete(st.sessions, id)
+		}
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,186 @@
+// Package sessions keeps per-client state between requests, in a cookie
+// (CookieStore) or on the server with the cookie holding only an ID
+// (MemoryStore).
+//
+//	r.Use(sessions.Sessions("gee_session", store))
+//	r.GET("/", func(ctx *gee.Context) {
+//		s := sessions.Default(ctx)
+//		s.Set("visits", s.GetInt("visits")+1)
+//		s.Save()
+//		...
+//	})
+//
+// Values are encoded with encoding/gob, register custom types with gob.Register.
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"gee"
+	"net/http"
+	"time"
+)
+
+// DefaultKey is the Context key holding the session of Sessions
+const DefaultKey = "gee/sessions"
+
+// Options are the attributes of the session cookie
+type Options struct {
+	Path   string
+	Domain string
+	// MaxAge is in seconds, 0 makes a browser session cookie and a
+	// negative value deletes the session on Save
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+func defaultOptions(maxAge time.Duration) Options {
+	return Options{
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Store loads and saves sessions
+type Store interface {
+	// Get returns the named session of the request, a new empty one when
+	// the request has none. The new session comes with the error when the
+	// cookie can't be decoded
+	Get(ctx *gee.Context, name string) (*Session, error)
+	// Save persists s and sets its cookie, so it must be called before the
+	// response body is written
+	Save(ctx *gee.Context, s *Session) error
+}
+
+// Session holds the values of one client
+type Session struct {
+	// ID identifies server side sessions, it is empty for cookie sessions
+	ID      string
+	Values  map[string]interface{}
+	Options Options
+	// IsNew is true when the request carried no valid session
+	IsNew bool
+
+	name  string
+	store Store
+	ctx   *gee.Context
+}
+
+// NewSession returns an empty session, for Store implementations
+func NewSession(ctx *gee.Context, store Store, name string, options Options) *Session {
+	return &Session{
+		Values:  make(map[string]interface{}),
+		Options: options,
+		IsNew:   true,
+		name:    name,
+		store:   store,
+		ctx:     ctx,
+	}
+}
+
+// Name returns the name of the session cookie
+func (s *Session) Name() string {
+	return s.name
+}
+
+// Get returns the value of key, nil if there is none
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+// GetString returns the value of key if it is a string
+func (s *Session) GetString(key string) string {
+	v, _ := s.Values[key].(string)
+	return v
+}
+
+// GetInt returns the value of key if it is an int
+func (s *Session) GetInt(key string) int {
+	v, _ := s.Values[key].(int)
+	return v
+}
+
+// Set sets key to value, the session must then be saved with Save
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+}
+
+// Delete removes key
+func (s *Session) Delete(key string) {
+	delete(s.Values, key)
+}
+
+// Clear removes every value
+func (s *Session) Clear() {
+	for key := range s.Values {
+		delete(s.Values, key)
+	}
+}
+
+// Save persists the session, before the response body is written
+func (s *Session) Save() error {
+	return s.store.Save(s.ctx, s)
+}
+
+type lazySession struct {
+	ctx     *gee.Context
+	name    string
+	store   Store
+	session *Session
+}
+
+func (l *lazySession) get() *Session {
+	if l.session == nil {
+		s, err := l.store.Get(l.ctx, l.name)
+		if err != nil {
+			// a bad cookie only means a fresh session
+			l.ctx.Error(err)
+		}
+		l.session = s
+	}
+	return l.session
+}
+
+// Sessions makes the session named name available to the handlers through
+// Default, it is only loaded from store when first used
+func Sessions(name string, store Store) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		ctx.Set(DefaultKey, &lazySession{ctx: ctx, name: name, store: store})
+		ctx.Next()
+	}
+}
+
+// Default returns the session of the request, it panics when the Sessions
+// middleware isn't in the chain
+func Default(ctx *gee.Context) *Session {
+	return ctx.MustGet(DefaultKey).(*lazySession).get()
+}
+
+func encodeValues(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeValues(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func setCookie(ctx *gee.Context, name, value string, options Options) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+		SameSite: options.SameSite,
+	})
+}
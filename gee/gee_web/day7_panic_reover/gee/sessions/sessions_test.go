@@ -0,0 +1,99 @@
+package sessions
+
+import (
+	"gee"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSessionEngine(store Store) *gee.Engine {
+	r := gee.New()
+	r.Use(Sessions("gee_session", store))
+	r.GET("/visit", func(ctx *gee.Context) {
+		s := Default(ctx)
+		s.Set("visits", s.GetInt("visits")+1)
+		if err := s.Save(); err != nil {
+			ctx.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.String(http.StatusOK, "%d", s.GetInt("visits"))
+	})
+	r.GET("/logout", func(ctx *gee.Context) {
+		s := Default(ctx)
+		s.Options.MaxAge = -1
+		s.Save()
+		ctx.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+// visit requests path with cookie and returns the body and the new cookie
+func visit(r *gee.Engine, path string, cookie *http.Cookie) (string, *http.Cookie) {
+	req := httptest.NewRequest("GET", path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 {
+		return w.Body.String(), nil
+	}
+	return w.Body.String(), cookies[0]
+}
+
+func testStore(t *testing.T, store Store, now *time.Time) {
+	r := newSessionEngine(store)
+
+	body, cookie := visit(r, "/visit", nil)
+	if body != "1" || cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected first visit %q %+v", body, cookie)
+	}
+	if body, cookie = visit(r, "/visit", cookie); body != "2" {
+		t.Fatalf("expected the second visit, got %q", body)
+	}
+	if body, _ = visit(r, "/visit", &http.Cookie{Name: "gee_session", Value: "forged"}); body != "1" {
+		t.Fatalf("a forged cookie should start a new session, got %q", body)
+	}
+
+	*now = now.Add(time.Duration(cookie.MaxAge+1) * time.Second)
+	if body, _ = visit(r, "/visit", cookie); body != "1" {
+		t.Fatalf("an expired session should start over, got %q", body)
+	}
+
+	_, cookie = visit(r, "/visit", nil)
+	if _, deleted := visit(r, "/logout", cookie); deleted == nil || deleted.MaxAge >= 0 {
+		t.Fatalf("logout should delete the cookie, got %+v", deleted)
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	codec, _ := NewEncryptedCodec([]byte("0123456789abcdef0123456789abcdef"))
+	store := NewCookieStore(codec)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	testStore(t, store, &now)
+
+	s := NewSession(nil, store, "big", store.Options)
+	s.Set("data", strings.Repeat("x", maxCookieSize))
+	if err := store.Save(nil, s); err != ErrCookieTooLarge {
+		t.Fatalf("expected ErrCookieTooLarge, got %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	testStore(t, store, &now)
+
+	// the logged out session is gone, the expired ones are swept lazily
+	now = now.Add(2 * time.Hour)
+	visit(newSessionEngine(store), "/visit", nil)
+	if store.Len() != 1 {
+		t.Fatalf("expected expired sessions to be swept, %d left", store.Len())
+	}
+}
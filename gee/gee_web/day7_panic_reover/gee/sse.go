@@ -0,0 +1,104 @@
+package gee
+
+import (
+	"bytes"
+	"gee/render"
+	"io"
+	"time"
+)
+
+// HeaderLastEventID is sent by EventSource clients when they reconnect
+const HeaderLastEventID = "Last-Event-ID"
+
+// LastEventID returns the id of the last event received by a reconnecting
+// EventSource client, so the stream can resume after it
+func (c *Context) LastEventID() string {
+	return c.Req.Header.Get(HeaderLastEventID)
+}
+
+// SSEvent sends a Server-Sent Event named name and flushes it, data is
+// sent as is when it is a string or []byte, as JSON otherwise
+func (c *Context) SSEvent(name string, data interface{}) {
+	c.WriteEvent(render.SSEvent{Event: name, Data: data})
+}
+
+// WriteEvent sends ev and flushes it. The stream headers are sent with the
+// first event, encoding errors are recorded with Error and skip the event
+func (c *Context) WriteEvent(ev render.SSEvent) {
+	var buf bytes.Buffer
+	if err := ev.Render(&buf); err != nil {
+		c.Error(err).SetType(ErrorTypeRender)
+		return
+	}
+	c.startEventStream()
+	c.Writer.Write(buf.Bytes())
+	c.Writer.Flush()
+}
+
+// startEventStream sets the headers of an event stream, unless the
+// response has started
+func (c *Context) startEventStream() {
+	if c.Writer.Written() {
+		return
+	}
+	header := c.Writer.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/event-stream")
+	}
+	header.Set("Cache-Control", "no-cache")
+	// tell nginx not to buffer the stream
+	header.Set("X-Accel-Buffering", "no")
+}
+
+// Stream calls step with the response body and flushes after each call,
+// until step returns false or the client goes away. It reports whether
+// the client went away
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+		}
+		keepOpen := step(c.Writer)
+		c.Writer.Flush()
+		if !keepOpen {
+			return false
+		}
+	}
+}
+
+// StreamEvents sends the events of ch until ch is closed or the client
+// goes away, which it reports. When heartbeat is positive, a comment is
+// sent after heartbeat without events, so proxies keep the connection open
+func (c *Context) StreamEvents(ch <-chan render.SSEvent, heartbeat time.Duration) bool {
+	c.startEventStream()
+	c.Writer.Flush()
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker = time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case ev, ok := <-ch:
+			if !ok {
+				return false
+			}
+			c.WriteEvent(ev)
+			if ticker != nil {
+				ticker.Reset(heartbeat)
+			}
+		case <-tick:
+			io.WriteString(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package gee
+
+import (
+	"bufio"
+	"context"
+	"gee/render"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEvent(t *testing.T) {
+	r := New()
+	r.GET("/events", func(ctx *Context) {
+		ctx.SSEvent("greeting", "hello")
+		ctx.SSEvent("user", H{"name": "gee"})
+		ctx.SSEvent("bad", make(chan int))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	expected := "event: greeting\ndata: hello\n\nevent: user\ndata: {\"name\":\"gee\"}\n\n"
+	if w.Body.String() != expected || w.Header().Get("Content-Type") != "text/event-stream" ||
+		w.Header().Get("Cache-Control") != "no-cache" || !w.Flushed {
+		t.Fatalf("unexpected stream %q %v", w.Body.String(), w.Header())
+	}
+}
+
+func TestStream(t *testing.T) {
+	r := New()
+	var gone bool
+	r.GET("/count", func(ctx *Context) {
+		i := 0
+		gone = ctx.Stream(func(w io.Writer) bool {
+			i++
+			io.WriteString(w, strings.Repeat("x", i))
+			return i < 3
+		})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/count", nil))
+	if w.Body.String() != "xxxxxx" || gone {
+		t.Fatalf("unexpected stream %q, client gone %v", w.Body.String(), gone)
+	}
+
+	r.GET("/forever", func(ctx *Context) {
+		gone = ctx.Stream(func(w io.Writer) bool {
+			return true
+		})
+	})
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/forever", nil).WithContext(reqCtx))
+	if !gone {
+		t.Fatal("Stream should stop once the client is gone")
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	r := New()
+	r.GET("/feed", func(ctx *Context) {
+		ch := make(chan render.SSEvent)
+		go func() {
+			defer close(ch)
+			// resume after the last event seen by the client
+			last := ctx.LastEventID()
+			for _, id := range []string{"1", "2", "3"} {
+				if id > last {
+					ch <- render.SSEvent{ID: id, Data: "event " + id}
+					time.Sleep(30 * time.Millisecond)
+				}
+			}
+		}()
+		ctx.StreamEvents(ch, 10*time.Millisecond)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/feed", nil)
+	req.Header.Set(HeaderLastEventID, "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var ids, heartbeats int
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		switch line := scanner.Text(); {
+		case strings.HasPrefix(line, "id: "):
+			ids++
+			if line == "id: 1" {
+				t.Fatal("event 1 was already seen by the client")
+			}
+		case line == ": heartbeat":
+			heartbeats++
+		}
+	}
+	if ids != 2 || heartbeats == 0 {
+		t.Fatalf("expected events 2 and 3 with heartbeats, got %d events %d heartbeats", ids, heartbeats)
+	}
+}
@@ -0,0 +1,238 @@
+package gee
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticConfig configures StaticWithConfig
+type StaticConfig struct {
+	// Root holds the files, e.g. http.Dir("static") or http.FS(embedFS)
+	Root http.FileSystem
+	// Browse lists the directories without an index.html
+	Browse bool
+	// MaxAge sets Cache-Control: public, max-age=MaxAge. Responses carry
+	// an ETag, and Last-Modified when Root has modification times
+	MaxAge time.Duration
+	// Precompressed serves file.br or file.gz next to file, when present
+	// and accepted by the client
+	Precompressed bool
+	// SPA serves /index.html for missing paths without an extension, so a
+	// single-page app can route them on the client
+	SPA bool
+
+	// etags caches the content ETags of files without a ModTime, such as
+	// those of an embed.FS, which can't change while the process runs
+	etags *sync.Map
+}
+
+// etagKey identifies a file of Root in StaticConfig.etags
+type etagKey struct {
+	name string
+	size int64
+}
+
+// precompressedEncodings are tried in order of preference
+var precompressedEncodings = []struct {
+	name, ext string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Static serves the files of the root directory under relativePath,
+// e.g. r.Static("/assets", "./static")
+func (group *RouterGroup) Static(relativePath string, root string) {
+	group.StaticWithConfig(relativePath, StaticConfig{Root: http.Dir(root)})
+}
+
+// StaticFS serves the files of fs under relativePath
+func (group *RouterGroup) StaticFS(relativePath string, fs http.FileSystem) {
+	group.StaticWithConfig(relativePath, StaticConfig{Root: fs})
+}
+
+// StaticFile serves a single file at relativePath, e.g. /favicon.ico
+func (group *RouterGroup) StaticFile(relativePath, file string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("gee: URL parameters can not be used when serving a static file")
+	}
+	root := http.Dir(filepath.Dir(file))
+	name := "/" + filepath.Base(file)
+	conf := StaticConfig{etags: new(sync.Map)}
+	group.GET(relativePath, func(ctx *Context) {
+		if !serveStaticFile(ctx, root, name, conf) {
+			staticNotFound(ctx)
+		}
+	})
+}
+
+// StaticWithConfig serves the files of conf.Root under relativePath
+func (group *RouterGroup) StaticWithConfig(relativePath string, conf StaticConfig) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("gee: URL parameters can not be used when serving a static folder")
+	}
+	if conf.Root == nil {
+		panic("gee: static root is nil")
+	}
+	conf.etags = new(sync.Map)
+	group.GET(path.Join(relativePath, "/*filepath"), group.createStaticHandler(relativePath, conf))
+}
+
+// create static handler
+func (group *RouterGroup) createStaticHandler(relativePath string, conf StaticConfig) HandlerFunc {
+	absolutePath := path.Join(group.prefix, relativePath)
+	// only used for directory listings
+	fileServer := http.StripPrefix(absolutePath, http.FileServer(conf.Root))
+
+	return func(ctx *Context) {
+		name := path.Clean("/" + ctx.Param("filepath"))
+		info, err := statFile(conf.Root, name)
+		switch {
+		case err == nil && info.IsDir():
+			if !strings.HasSuffix(ctx.Req.URL.Path, "/") {
+				redirectToDir(ctx)
+				return
+			}
+			if serveStaticFile(ctx, conf.Root, path.Join(name, "index.html"), conf) {
+				return
+			}
+			if conf.Browse {
+				fileServer.ServeHTTP(ctx.Writer, ctx.Req)
+				return
+			}
+		case err == nil:
+			if serveStaticFile(ctx, conf.Root, name, conf) {
+				return
+			}
+		case conf.SPA && path.Ext(name) == "":
+			// index.html links to versioned assets, it must be revalidated
+			ctx.SetHeader("Cache-Control", "no-cache")
+			conf.MaxAge = 0
+			if serveStaticFile(ctx, conf.Root, "/index.html", conf) {
+				return
+			}
+			ctx.Writer.Header().Del("Cache-Control")
+		}
+		staticNotFound(ctx)
+	}
+}
+
+func statFile(fs http.FileSystem, name string) (os.FileInfo, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// serveStaticFile serves the regular file name, or its precompressed
+// variant, and reports false when it doesn't exist
+func serveStaticFile(ctx *Context, fs http.FileSystem, name string, conf StaticConfig) bool {
+	header := ctx.Writer.Header()
+	if conf.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		accept := ctx.Req.Header.Get("Accept-Encoding")
+		for _, enc := range precompressedEncodings {
+			if !acceptsEncoding(accept, enc.name) {
+				continue
+			}
+			if f, info, ok := openRegularFile(fs, name+enc.ext); ok {
+				header.Set("Content-Encoding", enc.name)
+				serveContent(ctx, f, info, name+enc.ext, name, conf)
+				f.Close()
+				return true
+			}
+		}
+	}
+
+	f, info, ok := openRegularFile(fs, name)
+	if !ok {
+		return false
+	}
+	defer f.Close()
+	serveContent(ctx, f, info, name, name, conf)
+	return true
+}
+
+func openRegularFile(fs http.FileSystem, name string) (http.File, os.FileInfo, bool) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, nil, false
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, nil, false
+	}
+	return f, info, true
+}
+
+// serveContent lets http.ServeContent answer conditional and range
+// requests for the opened file, the Content-Type comes from the extension
+// of name
+func serveContent(ctx *Context, f http.File, info os.FileInfo, file, name string, conf StaticConfig) {
+	header := ctx.Writer.Header()
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+	if info.ModTime().IsZero() {
+		// embed.FS has no modification times, tag the content instead
+		key := etagKey{file, info.Size()}
+		if etag, ok := conf.etags.Load(key); ok {
+			header.Set("ETag", etag.(string))
+		} else {
+			h := sha1.New()
+			if _, err := io.Copy(h, f); err == nil {
+				etag := fmt.Sprintf(`"%x"`, h.Sum(nil))
+				conf.etags.Store(key, etag)
+				header.Set("ETag", etag)
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				ctx.Fail(http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+	} else {
+		header.Set("ETag", fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	}
+	if conf.MaxAge > 0 {
+		header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(conf.MaxAge/time.Second)))
+	}
+	http.ServeContent(ctx.Writer, ctx.Req, name, info.ModTime(), f)
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header accepts enc
+func acceptsEncoding(header, enc string) bool {
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), enc) {
+			continue
+		}
+		return len(params) == 1 || strings.Replace(strings.TrimSpace(params[1]), " ", "", -1) != "q=0"
+	}
+	return false
+}
+
+func redirectToDir(ctx *Context) {
+	target := path.Base(ctx.Req.URL.Path) + "/"
+	if ctx.Req.URL.RawQuery != "" {
+		target += "?" + ctx.Req.URL.RawQuery
+	}
+	ctx.SetHeader("Location", target)
+	ctx.Status(http.StatusMovedPermanently)
+}
+
+func staticNotFound(ctx *Context) {
+	ctx.String(http.StatusNotFound, "404 NOT FOUND: %s\n", ctx.Path)
+}
@@ -0,0 +1,198 @@
+package gee
+
+import (
+	"embed"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newStaticDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":      "<html>app</html>",
+		"app.js":          "console.log('gee')",
+		"app.js.gz":       "gzipped",
+		"app.js.br":       "brotli",
+		"docs/readme.txt": "readme",
+		"favicon.ico":     "icon",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func getStatic(r *Engine, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStatic(t *testing.T) {
+	dir := newStaticDir(t)
+	r := New()
+	r.Static("/static", dir)
+	r.StaticWithConfig("/browse", StaticConfig{Root: http.Dir(dir), Browse: true})
+	r.StaticFile("/favicon.ico", filepath.Join(dir, "favicon.ico"))
+
+	w := getStatic(r, "/static/app.js", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('gee')" || etag == "" ||
+		w.Header().Get("Last-Modified") == "" || !strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+		t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w = getStatic(r, "/static/app.js", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/static/", http.StatusOK, "<html>app</html>"},
+		{"/static/missing.js", http.StatusNotFound, "404 NOT FOUND: /static/missing.js\n"},
+		{"/static/docs/", http.StatusNotFound, "404 NOT FOUND: /static/docs/\n"},
+		{"/static/../gee.go", http.StatusNotFound, ""},
+		{"/browse/docs/", http.StatusOK, "readme.txt"},
+		{"/favicon.ico", http.StatusOK, "icon"},
+	}
+	for _, c := range cases {
+		w := getStatic(r, c.path, nil)
+		if w.Code != c.code || !strings.Contains(w.Body.String(), c.body) {
+			t.Errorf("%s: expected %d %q, got %d %q", c.path, c.code, c.body, w.Code, w.Body.String())
+		}
+	}
+
+	if w = getStatic(r, "/static/docs?x=1", nil); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "docs/?x=1" {
+		t.Fatalf("expected a redirect to the directory, got %d %v", w.Code, w.Header())
+	}
+}
+
+//go:embed testdata/static
+var embeddedStatic embed.FS
+
+func TestStaticEmbedFS(t *testing.T) {
+	sub, err := fs.Sub(embeddedStatic, "testdata/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.StaticFS("/assets", http.FS(sub))
+
+	w := getStatic(r, "/assets/app.css", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "body{color:red}\n" || etag == "" ||
+		!strings.Contains(w.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if other := getStatic(r, "/assets/app2.css", nil).Header().Get("ETag"); other == etag {
+		t.Fatalf("files of the same size share the ETag %s", etag)
+	}
+	if w = getStatic(r, "/assets/app.css", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+	if w = getStatic(r, "/assets/missing.css", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+// countingFS counts the bytes read from its files
+type countingFS struct {
+	http.FileSystem
+	read *int
+}
+
+type countingFile struct {
+	http.File
+	read *int
+}
+
+func (fs countingFS) Open(name string) (http.File, error) {
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return countingFile{f, fs.read}, nil
+}
+
+func (f countingFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	*f.read += n
+	return n, err
+}
+
+func TestStaticETagCache(t *testing.T) {
+	read := 0
+	r := New()
+	r.StaticFS("/assets", countingFS{http.FS(fstest.MapFS{"app.js": {Data: []byte("console.log('gee')")}}), &read})
+
+	first := getStatic(r, "/assets/app.js", nil).Header().Get("ETag")
+	afterFirst := read
+	second := getStatic(r, "/assets/app.js", nil).Header().Get("ETag")
+	if first == "" || first != second {
+		t.Fatalf("expected a stable content ETag, got %q and %q", first, second)
+	}
+	if served := read - afterFirst; served != len("console.log('gee')") {
+		t.Fatalf("the cached ETag should skip hashing, read %d bytes", served)
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	r := New()
+	r.StaticWithConfig("/assets", StaticConfig{Root: http.Dir(newStaticDir(t)), Precompressed: true, MaxAge: time.Hour})
+
+	cases := map[string]string{
+		"br, gzip":    "brotli",
+		"gzip":        "gzipped",
+		"br;q=0,gzip": "gzipped",
+		"":            "console.log('gee')",
+	}
+	for accept, expected := range cases {
+		w := getStatic(r, "/assets/app.js", map[string]string{"Accept-Encoding": accept})
+		if w.Body.String() != expected || w.Header().Get("Vary") != "Accept-Encoding" ||
+			w.Header().Get("Cache-Control") != "public, max-age=3600" ||
+			!strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+			t.Errorf("Accept-Encoding %q: unexpected response %q %v", accept, w.Body.String(), w.Header())
+		}
+	}
+	if w := getStatic(r, "/assets/app.js", map[string]string{"Accept-Encoding": "br"}); w.Header().Get("Content-Encoding") != "br" {
+		t.Errorf("expected Content-Encoding br, got %v", w.Header())
+	}
+}
+
+func TestStaticSPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<html>spa</html>")},
+		"assets/app.js": {Data: []byte("app")},
+	}
+	r := New()
+	r.StaticWithConfig("/", StaticConfig{Root: http.FS(fsys), SPA: true})
+
+	w := getStatic(r, "/users/1", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "<html>spa</html>" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("expected the index.html fallback, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w = getStatic(r, "/assets/app.js", nil); w.Body.String() != "app" {
+		t.Fatalf("unexpected asset %q", w.Body.String())
+	}
+	if w = getStatic(r, "/assets/missing.js", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing assets should stay 404, got %d", w.Code)
+	}
+}
@@ -1,69 +1,280 @@
 package gee
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
 
+// node is a vertex of the compressed radix tree used by the router.
+// Static text is shared between routes by common prefix (a static part may
+// span several path segments), while every :param and *catchall occupies a
+// whole segment in a child of its own.
+// 查找时优先级固定为：静态 > :param > *catchall，与注册顺序无关
 type node struct {
-	pattern  string  // 待匹配路由，例如 /p/:lang
-	part     string  // 路由中的一部分，例如 :lang
-	children []*node // 子节点，例如 [doc, tutorial, intro]
-	isWild   bool    // 是否精确匹配，part含有 : 或 * 时为true
+	pattern  string        // 待匹配路由，只在路由终点设置，例如 /p/:lang
+	part     string        // 静态前缀，例如 /p/，或通配段 :lang、*filepath
+	children []*node       // 静态子节点，首字节互不相同
+	indices  string        // children 的首字节，用于快速定位
+	params   []*node       // :param 子节点，带约束的在前，无约束的最多一个且在最后
+	catchAll *node         // *catchall 子节点
+	handlers []HandlerFunc // 注册时已合并好的中间件链 + 路由处理方法
+	group    *RouterGroup  // 注册路由的分组，用于查找 HTML 模板
+
+	paramName  string         // :param 的名字，不含约束
+	constraint *regexp.Regexp // :id<int> 的约束，nil 表示匹配任意段
 }
 
-// 第一个匹配成功的节点，用于插入
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// insertStatic walks or splits static children along s and returns the node
+// at which s ends
+func (n *node) insertStatic(s string) *node {
+	for len(s) > 0 {
+		i := strings.IndexByte(n.indices, s[0])
+		if i < 0 {
+			child := &node{part: s}
+			n.indices += s[:1]
+			n.children = append(n.children, child)
 			return child
 		}
+
+		child := n.children[i]
+		l := longestCommonPrefix(child.part, s)
+		if l < len(child.part) {
+			// split child so that the shared prefix becomes its own node
+			prefix := &node{
+				part:     child.part[:l],
+				indices:  child.part[l : l+1],
+				children: []*node{child},
+			}
+			child.part = child.part[l:]
+			n.children[i] = prefix
+			child = prefix
+		}
+		s = s[l:]
+		n = child
 	}
-	return nil
+	return n
 }
 
-// 所有匹配成功的节点，用于查找
-func (n *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
+// insertWild returns the :param or *catchall child named by part,
+// two different names at the same position are ambiguous and panic.
+// Constrained params (:id<int>) with different constraints may share a
+// position, they are tried in registration order before the unconstrained
+// one, so register the narrower of overlapping constraints first
+func (n *node) insertWild(part, pattern string) *node {
+	if len(part) < 2 && part[0] == ':' {
+		panic(fmt.Sprintf("gee: wildcard in route '%s' must be named", pattern))
+	}
+
+	if part[0] == '*' {
+		if n.catchAll == nil {
+			n.catchAll = &node{part: part}
+		} else if n.catchAll.part != part {
+			panic(fmt.Sprintf("gee: wildcard '%s' in route '%s' conflicts with existing wildcard '%s'",
+				part, pattern, n.catchAll.part))
+		}
+		return n.catchAll
+	}
+
+	for _, child := range n.params {
+		if child.part == part {
+			return child
+		}
+	}
+	name, constraint, err := parseParam(part)
+	if err != nil {
+		panic(fmt.Sprintf("gee: invalid constraint in route '%s': %v", pattern, err))
+	}
+	child := &node{part: part, paramName: name, constraint: constraint}
+	last := len(n.params) - 1
+	if constraint != nil {
+		for _, other := range n.params {
+			if other.constraint != nil && other.constraint.String() == constraint.String() {
+				panic(fmt.Sprintf("gee: wildcard '%s' in route '%s' conflicts with existing wildcard '%s'",
+					part, pattern, other.part))
+			}
 		}
+		// keep the unconstrained param last
+		if last >= 0 && n.params[last].constraint == nil {
+			n.params = append(n.params[:last], child, n.params[last])
+		} else {
+			n.params = append(n.params, child)
+		}
+		return child
+	}
+	if last >= 0 && n.params[last].constraint == nil {
+		panic(fmt.Sprintf("gee: wildcard '%s' in route '%s' conflicts with existing wildcard '%s'",
+			part, pattern, n.params[last].part))
+	}
+	n.params = append(n.params, child)
+	return child
+}
+
+// paramConstraints are the named constraints, anything else is a regexp
+var paramConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// parseParam splits :name<constraint> into its name and the regexp the
+// whole segment must match, nil without constraint
+func parseParam(part string) (string, *regexp.Regexp, error) {
+	i := strings.IndexByte(part, '<')
+	if i < 0 {
+		return part[1:], nil, nil
+	}
+	if !strings.HasSuffix(part, ">") || i == 1 || i == len(part)-2 {
+		return "", nil, fmt.Errorf("malformed parameter '%s'", part)
+	}
+	expr := part[i+1 : len(part)-1]
+	if named, ok := paramConstraints[expr]; ok {
+		expr = named
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return "", nil, err
 	}
-	return nodes
+	return part[1:i], re, nil
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// insert adds pattern to the tree and returns the node that terminates it
+func (n *node) insert(pattern string) *node {
+	if pattern == "" || pattern[0] != '/' {
+		panic(fmt.Sprintf("gee: route '%s' must begin with '/'", pattern))
 	}
 
-	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{
-			part:   part,
-			isWild: part[0] == ':' || part[0] == '*',
+	parts := parsePattern(pattern)
+	static := "/"
+	for i, part := range parts {
+		if i > 0 {
+			static += "/"
 		}
-		n.children = append(n.children, child)
+		if part[0] != ':' && part[0] != '*' {
+			static += part
+			continue
+		}
+		if part[0] == '*' && !strings.HasSuffix(pattern, "/"+part) {
+			panic(fmt.Sprintf("gee: catch-all '%s' must be the last segment of route '%s'", part, pattern))
+		}
+		n = n.insertStatic(static).insertWild(part, pattern)
+		static = ""
 	}
-	child.insert(pattern, parts, height+1)
+	if len(parts) > 0 && strings.HasSuffix(pattern, "/") {
+		static += "/"
+	}
+	n = n.insertStatic(static)
+
+	if n.pattern != "" {
+		panic(fmt.Sprintf("gee: route '%s' conflicts with existing route '%s'", pattern, n.pattern))
+	}
+	n.pattern = pattern
+	return n
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.pattern == "" {
-			return nil
+// search matches the rest of path below n, whose own part is already
+// consumed. A dead-end branch backtracks to the next candidate, so params
+// are only recorded on the way back from a successful match
+func (n *node) search(path string, params map[string]string) *node {
+	if path == "" {
+		if n.pattern != "" {
+			return n
+		}
+		// "/assets/" matches "/assets/*filepath" with an empty filepath
+		if n.catchAll != nil && n.catchAll.pattern != "" {
+			n.catchAll.setCatchAll("", params)
+			return n.catchAll
 		}
-		return n
+		return nil
 	}
 
-	part := parts[height]
-	children := n.matchChildren(part)
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.part) {
+			if result := child.search(path[len(child.part):], params); result != nil {
+				return result
+			}
+		}
+	}
 
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if result != nil {
-			return result
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			segment := path[:end]
+			for _, child := range n.params {
+				if child.constraint != nil && !child.constraint.MatchString(segment) {
+					continue
+				}
+				if result := child.search(path[end:], params); result != nil {
+					params[child.paramName] = segment
+					return result
+				}
+			}
 		}
 	}
+
+	if n.catchAll != nil && n.catchAll.pattern != "" {
+		n.catchAll.setCatchAll(path, params)
+		return n.catchAll
+	}
 	return nil
 }
+
+func (n *node) setCatchAll(value string, params map[string]string) {
+	// a bare "*" matches without capturing anything
+	if name := n.part[1:]; name != "" {
+		params[name] = value
+	}
+}
+
+// searchFold is search ignoring the case of static parts, for
+// RedirectFixedPath. It returns path with the static parts spelled as
+// registered, params and catch-alls are kept as requested
+func (n *node) searchFold(path string) (string, bool) {
+	if path == "" {
+		return "", n.pattern != "" || (n.catchAll != nil && n.catchAll.pattern != "")
+	}
+
+	// indices can't help, the first byte may differ in case
+	for _, child := range n.children {
+		if len(path) >= len(child.part) && strings.EqualFold(path[:len(child.part)], child.part) {
+			if rest, ok := child.searchFold(path[len(child.part):]); ok {
+				return child.part + rest, true
+			}
+		}
+	}
+
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			for _, child := range n.params {
+				if child.constraint != nil && !child.constraint.MatchString(path[:end]) {
+					continue
+				}
+				if rest, ok := child.searchFold(path[end:]); ok {
+					return path[:end] + rest, true
+				}
+			}
+		}
+	}
+
+	if n.catchAll != nil && n.catchAll.pattern != "" {
+		return path, true
+	}
+	return "", false
+}
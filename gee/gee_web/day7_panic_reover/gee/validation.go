@@ -0,0 +1,200 @@
+package gee
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a struct field that failed one `binding` rule
+type FieldError struct {
+	Field string      `json:"field"`           // field path, e.g. Address.City
+	Rule  string      `json:"rule"`            // failing rule, e.g. max
+	Param string      `json:"param,omitempty"` // rule parameter, e.g. 10 for max=10
+	Value interface{} `json:"-"`               // the offending value
+}
+
+func (e FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("field '%s' failed on the '%s' rule", e.Field, e.Rule)
+	}
+	return fmt.Sprintf("field '%s' failed on the '%s=%s' rule", e.Field, e.Rule, e.Param)
+}
+
+// ValidationErrors lists every failing field of a bound struct
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validate checks the `binding` tags of the struct obj points to, e.g.
+// `binding:"required,min=1,max=10,email"`. Supported rules: required,
+// omitempty, min, max, len (value for numbers, length otherwise), email
+// and oneof (space separated choices). Nested structs are checked too
+func validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if err := validateStruct(v, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name := prefix + field.Name
+		fv := v.Field(i)
+
+		if tag := field.Tag.Get("binding"); tag != "" && tag != "-" {
+			if err := validateField(fv, name, strings.Split(tag, ","), errs); err != nil {
+				return err
+			}
+		}
+
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			if err := validateStruct(fv, name+".", errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateField(v reflect.Value, name string, rules []string, errs *ValidationErrors) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+
+	empty := isEmptyValue(v)
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		param := ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			rule, param = rule[:i], rule[i+1:]
+		}
+
+		if v.Kind() == reflect.Ptr && rule != "required" && rule != "omitempty" {
+			continue // a nil pointer can only fail "required"
+		}
+
+		var ok bool
+		switch rule {
+		case "omitempty":
+			if empty {
+				return nil
+			}
+			continue
+		case "required":
+			ok = !empty
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return fmt.Errorf("gee: invalid parameter for rule '%s' on field %s", rule, name)
+			}
+			size, measurable := measure(v)
+			if !measurable {
+				return fmt.Errorf("gee: rule '%s' cannot be applied to field %s of type %s", rule, name, v.Type())
+			}
+			switch rule {
+			case "min":
+				ok = size >= n
+			case "max":
+				ok = size <= n
+			default:
+				ok = size == n
+			}
+		case "email":
+			ok = v.Kind() == reflect.String && isEmail(v.String())
+		case "oneof":
+			value := fmt.Sprint(valueOf(v))
+			for _, choice := range strings.Fields(param) {
+				if value == choice {
+					ok = true
+					break
+				}
+			}
+		default:
+			return fmt.Errorf("gee: unknown binding rule '%s' on field %s", rule, name)
+		}
+
+		if !ok {
+			*errs = append(*errs, FieldError{Field: name, Rule: rule, Param: param, Value: valueOf(v)})
+			// later rules on an empty or invalid field only repeat the same failure
+			return nil
+		}
+	}
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// measure is the value of numbers and the length of strings and collections
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && strings.Contains(s[strings.LastIndexByte(s, '@'):], ".")
+}
+
+func valueOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
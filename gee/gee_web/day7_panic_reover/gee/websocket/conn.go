@@ -0,0 +1,444 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, the values are the frame opcodes of RFC 6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes, see RFC 6455 section 7.4.1
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+const (
+	finalBit = 0x80
+	rsvBits  = 0x70
+	maskBit  = 0x80
+
+	maxControlPayload = 125
+	// writeFragmentSize is the payload size of the frames of NextWriter
+	writeFragmentSize = 4096
+)
+
+var (
+	// ErrReadLimit is returned when a message exceeds the max message size
+	ErrReadLimit = errors.New("websocket: message too big")
+	// ErrCloseSent is returned when writing after a close message
+	ErrCloseSent = errors.New("websocket: close sent")
+)
+
+// CloseError is returned by ReadMessage once the peer closed the connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// protocolError is a violation of RFC 6455 by the peer
+type protocolError struct {
+	code    int
+	message string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.message
+}
+
+// Conn is a WebSocket connection
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	readLimit   int64
+	subprotocol string
+
+	pingHandler func(appData string) error
+	pongHandler func(appData string) error
+
+	// frames are written whole under writeMu, so a pong sent by the reader
+	// can slip between the fragments of a message but never inside a frame
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool, readLimit int64) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{conn: conn, br: br, isServer: isServer, readLimit: readLimit}
+	c.pingHandler = func(appData string) error {
+		err := c.WriteMessage(PongMessage, []byte(appData))
+		if err == ErrCloseSent {
+			return nil
+		}
+		return err
+	}
+	c.pongHandler = func(string) error { return nil }
+	return c
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the address of the peer
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the max size of the messages read
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline of the next reads, e.g. to drop
+// clients that stopped answering pings
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline of the next writes
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler handles the pings received by ReadMessage, by default
+// they are answered with a pong carrying the same data
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler handles the pongs received by ReadMessage
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	c.pongHandler = h
+}
+
+// Close closes the underlying connection without a close handshake,
+// see WriteClose
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// FormatCloseMessage returns the payload of a close message
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	payload := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], text)
+	return payload
+}
+
+// WriteClose starts the close handshake, keep calling ReadMessage until
+// it returns the CloseError echoed by the peer, then Close
+func (c *Conn) WriteClose(code int, text string) error {
+	return c.WriteMessage(CloseMessage, FormatCloseMessage(code, text))
+}
+
+// WriteMessage writes data as a single frame message
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return errors.New("websocket: control message payload too long")
+		}
+	default:
+		return fmt.Errorf("websocket: unknown message type %d", messageType)
+	}
+	return c.writeFrame(true, messageType, data)
+}
+
+// NextWriter returns a writer sending a text or binary message in
+// fragments as it is written, the message ends with Close
+func (c *Conn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, fmt.Errorf("websocket: can't fragment message type %d", messageType)
+	}
+	return &messageWriter{c: c, opcode: messageType, buf: make([]byte, 0, writeFragmentSize)}, nil
+}
+
+type messageWriter struct {
+	c      *Conn
+	opcode int
+	buf    []byte
+	closed bool
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("websocket: write to closed writer")
+	}
+	n := 0
+	for len(p) > 0 {
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+func (w *messageWriter) flush(final bool) error {
+	err := w.c.writeFrame(final, w.opcode, w.buf)
+	w.opcode = continuationFrame
+	w.buf = w.buf[:0]
+	return err
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+func (c *Conn) writeFrame(final bool, opcode int, payload []byte) error {
+	header := make([]byte, 2, 14)
+	header[0] = byte(opcode)
+	if final {
+		header[0] |= finalBit
+	}
+	switch n := len(payload); {
+	case n <= maxControlPayload:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	var key [4]byte
+	if !c.isServer {
+		// clients mask every frame, RFC 6455 section 5.3
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		header[1] |= maskBit
+		header = append(header, key[:]...)
+	}
+	frame := append(header, payload...)
+	if !c.isServer {
+		maskBytes(key, frame[len(header):])
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// ReadMessage returns the next text or binary message, reassembled from
+// its fragments. Pings and pongs are handled on the way. Once the peer
+// closes, the close is echoed and a *CloseError returned. Protocol
+// violations close the connection with the matching code
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	messageType, data, err = c.readMessage()
+	if perr, ok := err.(*protocolError); ok {
+		c.WriteClose(perr.code, perr.message)
+	}
+	return messageType, data, err
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		final, opcode, payload, err := c.readFrame(c.readLimit - int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.pingHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, &protocolError{CloseProtocolError, "new message before the end of a fragmented one"}
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, &protocolError{CloseProtocolError, "continuation frame without a message"}
+			}
+		default:
+			return 0, nil, &protocolError{CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode)}
+		}
+
+		message = append(message, payload...)
+		if !final {
+			continue
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, &protocolError{CloseInvalidPayload, "invalid UTF-8 in text message"}
+		}
+		if message == nil {
+			message = []byte{}
+		}
+		return messageType, message, nil
+	}
+}
+
+// handleClose echoes the close of the peer, unless we started the handshake
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return &protocolError{CloseProtocolError, "invalid close payload"}
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return &protocolError{CloseProtocolError, "invalid close code"}
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return &protocolError{CloseInvalidPayload, "invalid UTF-8 in close reason"}
+		}
+	}
+	echo := FormatCloseMessage(closeErr.Code, "")
+	if err := c.WriteMessage(CloseMessage, echo); err != nil && err != ErrCloseSent {
+		return err
+	}
+	return closeErr
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code < 5000:
+		return true
+	}
+	return false
+}
+
+// readFrame reads one frame whose payload, for a data frame, fits in remaining
+func (c *Conn) readFrame(remaining int64) (final bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	final = header[0]&finalBit != 0
+	opcode = int(header[0] & 0x0f)
+	masked := header[1]&maskBit != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&rsvBits != 0 {
+		return false, 0, nil, &protocolError{CloseProtocolError, "unexpected reserved bits"}
+	}
+	if masked != c.isServer {
+		return false, 0, nil, &protocolError{CloseProtocolError, "bad frame masking"}
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		if ext[0]&0x80 != 0 {
+			return false, 0, nil, &protocolError{CloseProtocolError, "invalid payload length"}
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= CloseMessage {
+		if length > maxControlPayload || !final {
+			return false, 0, nil, &protocolError{CloseProtocolError, "invalid control frame"}
+		}
+	} else if length > remaining {
+		// refuse before allocating the payload
+		return false, 0, nil, c.tooBig()
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(key, payload)
+	}
+	return final, opcode, payload, nil
+}
+
+func (c *Conn) tooBig() error {
+	c.WriteClose(CloseMessageTooBig, "")
+	return ErrReadLimit
+}
@@ -0,0 +1,187 @@
+// Package websocket implements RFC 6455 WebSocket connections for gee
+// handlers.
+//
+//	upgrader := &websocket.Upgrader{}
+//	r.GET("/ws", func(ctx *gee.Context) {
+//		conn, err := upgrader.Upgrade(ctx)
+//		if err != nil {
+//			return // the handshake error has been answered
+//		}
+//		defer conn.Close()
+//		for {
+//			typ, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			conn.WriteMessage(typ, msg)
+//		}
+//	})
+//
+// A Conn supports one concurrent reader and one concurrent writer.
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"gee"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// keyGUID is appended to Sec-WebSocket-Key to compute Sec-WebSocket-Accept
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// defaultMaxMessageSize bounds the messages read when the Upgrader doesn't
+const defaultMaxMessageSize = 1 << 20
+
+// HandshakeError is returned by Upgrade when the request isn't a valid
+// WebSocket handshake, the client has already been answered
+type HandshakeError struct {
+	message string
+}
+
+func (e HandshakeError) Error() string {
+	return "websocket: " + e.message
+}
+
+// Upgrader upgrades HTTP requests to WebSocket connections
+type Upgrader struct {
+	// CheckOrigin accepts the Origin of the request, by default only the
+	// requests without Origin or from the same host are accepted, which
+	// stops other sites from opening connections with the user's cookies
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols are the supported subprotocols in order of preference
+	Subprotocols []string
+	// MaxMessageSize bounds the size of the messages read, 1MB by default
+	MaxMessageSize int64
+}
+
+// Upgrade performs the opening handshake and takes over the connection,
+// nothing must be written with ctx afterwards. A failed handshake is
+// answered with a 4xx status and returned as a HandshakeError, a
+// connection that can't be hijacked with 500
+func (u *Upgrader) Upgrade(ctx *gee.Context) (*Conn, error) {
+	r := ctx.Req
+	fail := func(code int, message string) (*Conn, error) {
+		ctx.String(code, "%s\n", http.StatusText(code))
+		ctx.Abort()
+		return nil, HandshakeError{message}
+	}
+
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return fail(http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		ctx.SetHeader("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+	subprotocol := u.selectSubprotocol(r)
+
+	// the status is only for the access log, the 101 is written by hand
+	ctx.Status(http.StatusSwitchingProtocols)
+	netConn, brw, err := ctx.Writer.Hijack()
+	if err != nil {
+		// e.g. HTTP/2, nothing was sent yet so answer instead of a bare 101
+		ctx.String(http.StatusInternalServerError, "%s\n", http.StatusText(http.StatusInternalServerError))
+		ctx.Abort()
+		return nil, err
+	}
+	if brw.Writer.Buffered() > 0 {
+		netConn.Close()
+		return nil, errors.New("websocket: data written before the handshake")
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n"
+	if subprotocol != "" {
+		response += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	response += "\r\n"
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	maxMessageSize := u.MaxMessageSize
+	if maxMessageSize <= 0 {
+		maxMessageSize = defaultMaxMessageSize
+	}
+	conn := newConn(netConn, brw.Reader, true, maxMessageSize)
+	conn.subprotocol = subprotocol
+	return conn, nil
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	requested := headerTokens(r.Header, "Sec-WebSocket-Protocol")
+	for _, supported := range u.Subprotocols {
+		for _, protocol := range requested {
+			if protocol == supported {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
+
+// IsWebSocketUpgrade reports whether r asks for a WebSocket upgrade
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + keyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, t := range headerTokens(header, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"gee"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dial is a minimal client: it sends the opening handshake and wraps the
+// connection in a client side Conn
+func dial(t *testing.T, server *httptest.Server, header http.Header) (*Conn, net.Conn) {
+	t.Helper()
+	netConn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	netConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req, _ := http.NewRequest("GET", server.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := req.Write(netConn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+	conn := newConn(netConn, br, false, 1<<20)
+	conn.subprotocol = resp.Header.Get("Sec-WebSocket-Protocol")
+	return conn, netConn
+}
+
+// newEchoServer echoes every message and reports the error ending each
+// connection on errs
+func newEchoServer(upgrader *Upgrader) (*httptest.Server, chan error) {
+	errs := make(chan error, 1)
+	r := gee.New()
+	r.GET("/ws", func(ctx *gee.Context) {
+		conn, err := upgrader.Upgrade(ctx)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := conn.WriteMessage(typ, msg); err != nil {
+				errs <- err
+				return
+			}
+		}
+	})
+	return httptest.NewServer(r), errs
+}
+
+func TestEcho(t *testing.T) {
+	server, errs := newEchoServer(&Upgrader{Subprotocols: []string{"chat"}})
+	defer server.Close()
+	conn, _ := dial(t, server, http.Header{"Sec-Websocket-Protocol": {"superchat, chat"}})
+	defer conn.Close()
+	if conn.Subprotocol() != "chat" {
+		t.Fatalf("expected subprotocol chat, got %q", conn.Subprotocol())
+	}
+
+	var pong string
+	conn.SetPongHandler(func(appData string) error {
+		pong = appData
+		return nil
+	})
+	if err := conn.WriteMessage(PingMessage, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	conn.WriteMessage(TextMessage, []byte("hello gee"))
+	if typ, msg, err := conn.ReadMessage(); err != nil || typ != TextMessage || string(msg) != "hello gee" || pong != "hi" {
+		t.Fatalf("unexpected echo %d %q %v, pong %q", typ, msg, err, pong)
+	}
+
+	// fragmented over several frames, the echo comes back as a 64KB+ frame
+	large := bytes.Repeat([]byte("gee"), 30000)
+	w, _ := conn.NextWriter(BinaryMessage)
+	w.Write(large[:5000])
+	w.Write(large[5000:])
+	w.Close()
+	if typ, msg, err := conn.ReadMessage(); err != nil || typ != BinaryMessage || !bytes.Equal(msg, large) {
+		t.Fatalf("unexpected fragmented echo %d %d bytes %v", typ, len(msg), err)
+	}
+
+	if err := conn.WriteClose(CloseNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err.(*CloseError).Code != CloseNormalClosure || err.(*CloseError).Text != "bye" {
+		t.Fatalf("server expected close 1000 bye, got %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); err == nil || err.(*CloseError).Code != CloseNormalClosure {
+		t.Fatalf("client expected the close echo, got %v", err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("late")); err != ErrCloseSent {
+		t.Fatalf("expected ErrCloseSent, got %v", err)
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	server, errs := newEchoServer(&Upgrader{MaxMessageSize: 16})
+	defer server.Close()
+	conn, _ := dial(t, server, nil)
+	defer conn.Close()
+
+	conn.WriteMessage(TextMessage, []byte(strings.Repeat("x", 17)))
+	if err := <-errs; err != ErrReadLimit {
+		t.Fatalf("expected ErrReadLimit, got %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); err == nil || err.(*CloseError).Code != CloseMessageTooBig {
+		t.Fatalf("expected close 1009, got %v", err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	frames := map[string][]byte{
+		"unmasked":         {0x81, 0x02, 'h', 'i'},
+		"reserved bits":    {0xc1, 0x80, 0, 0, 0, 0},
+		"bad continuation": {0x80, 0x80, 0, 0, 0, 0},
+		"invalid utf-8":    {0x81, 0x81, 0, 0, 0, 0, 0xff},
+	}
+	for name, frame := range frames {
+		server, errs := newEchoServer(&Upgrader{})
+		conn, netConn := dial(t, server, nil)
+		netConn.Write(frame)
+
+		<-errs
+		expected := CloseProtocolError
+		if name == "invalid utf-8" {
+			expected = CloseInvalidPayload
+		}
+		if _, _, err := conn.ReadMessage(); err == nil || err.(*CloseError).Code != expected {
+			t.Errorf("%s: expected close %d, got %v", name, expected, err)
+		}
+		conn.Close()
+		server.Close()
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	r := gee.New()
+	r.GET("/ws", func(ctx *gee.Context) {
+		if _, err := (&Upgrader{}).Upgrade(ctx); err == nil {
+			t.Error("expected a handshake error")
+		}
+	})
+
+	valid := http.Header{
+		"Connection":            {"keep-alive, Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+	}
+	cases := []struct {
+		name, header, value string
+		code                int
+	}{
+		{"no upgrade", "Upgrade", "", http.StatusBadRequest},
+		{"old version", "Sec-Websocket-Version", "8", http.StatusUpgradeRequired},
+		{"bad key", "Sec-Websocket-Key", "short", http.StatusBadRequest},
+		{"cross origin", "Origin", "https://evil.com", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/ws", nil)
+		for name, values := range valid {
+			req.Header[name] = values
+		}
+		req.Header.Set(c.header, c.value)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("%s: expected %d, got %d", c.name, c.code, w.Code)
+		}
+	}
+
+	// a valid handshake on a ResponseWriter without http.Hijacker
+	req := httptest.NewRequest("GET", "/ws", nil)
+	for name, values := range valid {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("a failed hijack should answer 500, got %d", w.Code)
+	}
+
+	if key := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); key != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %s, see RFC 6455 section 1.3", key)
+	}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"gee"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -30,7 +34,21 @@ func main() {
 		ctx.String(http.StatusOK, names[100])
 	})
 
-	r.Run(":9999")
+	go func() {
+		if err := r.Run(":9999"); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// drain in-flight requests before exiting on SIGTERM / Ctrl+C
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
 }
 
 func onlyForV2() gee.HandlerFunc {
@@ -40,6 +58,6 @@ func onlyForV2() gee.HandlerFunc {
 		// if a server error occurred
 		ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
 		// calculate resolution time
-		log.Printf("[%d] %s in %v for group v2", ctx.StatusCode, ctx.Req.RequestURI, time.Since(t))
+		log.Printf("[%d] %s in %v for group v2", ctx.Writer.Status(), ctx.Req.RequestURI, time.Since(t))
 	}
 }